@@ -2,17 +2,23 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
+	"upwork-buddy/internal/config"
+	"upwork-buddy/internal/lifecycle"
+	"upwork-buddy/internal/logging"
 	"upwork-buddy/internal/server"
+	"upwork-buddy/internal/tracing"
 )
 
-func gracefulShutdown(apiServer *http.Server, done chan bool) {
+func gracefulShutdown(lc *lifecycle.Manager, done chan bool) {
 	// Create context that listens for the interrupt signal from the OS.
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
@@ -20,39 +26,57 @@ func gracefulShutdown(apiServer *http.Server, done chan bool) {
 	// Listen for the interrupt signal.
 	<-ctx.Done()
 
-	log.Println("shutting down gracefully, press Ctrl+C again to force")
+	slog.Info("shutting down gracefully, press Ctrl+C again to force")
 	stop() // Allow Ctrl+C to force shutdown
 
-	// The context is used to inform the server it has 5 seconds to finish
-	// the request it is currently handling
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := apiServer.Shutdown(ctx); err != nil {
-		log.Printf("Server forced to shutdown with error: %v", err)
+	// Stop taking work, drain in-flight analyses, then close the LLM
+	// client, the database pool and the trace exporter
+	if err := lc.Shutdown(); err != nil {
+		slog.Error("shutdown finished with errors", "error", err)
 	}
 
-	log.Println("Server exiting")
-
 	// Notify the main goroutine that the shutdown is complete
 	done <- true
 }
 
 func main() {
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	logging.Setup(logging.Options{Level: cfg.Log.Level, Format: cfg.Log.Format})
+
+	lc := lifecycle.New(cfg.Server.ShutdownTimeout)
 
-	server := server.NewServer()
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		slog.Error("tracing disabled", "error", err)
+	}
+
+	server := server.NewServer(cfg, lc)
+
+	// Flush the spans of the last requests once everything else is closed
+	if shutdownTracing != nil {
+		lc.OnShutdown(lifecycle.StageClose, "tracing", shutdownTracing)
+	}
 
 	// Create a done channel to signal when the shutdown is complete
 	done := make(chan bool, 1)
 
 	// Run graceful shutdown in a separate goroutine
-	go gracefulShutdown(server, done)
+	go gracefulShutdown(lc, done)
 
-	err := server.ListenAndServe()
+	err = server.ListenAndServe()
 	if err != nil && err != http.ErrServerClosed {
 		panic(fmt.Sprintf("http server error: %s", err))
 	}
 
 	// Wait for the graceful shutdown to complete
 	<-done
-	log.Println("Graceful shutdown complete.")
+	slog.Info("graceful shutdown complete")
 }
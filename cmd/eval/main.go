@@ -0,0 +1,125 @@
+// Command eval replays a JSONL corpus of job analysis requests through one or
+// two provider configurations, scores the outputs and writes a comparison
+// report.
+//
+//	go run ./cmd/eval -corpus cmd/eval/testdata/corpus.jsonl \
+//	    -baseline prompt=v1 -candidate prompt=v2 -judge -out report.md
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	_ "github.com/joho/godotenv/autoload"
+
+	"upwork-buddy/internal/eval"
+	"upwork-buddy/internal/gemini"
+)
+
+func main() {
+	var (
+		corpusPath = flag.String("corpus", "", "path to a JSONL file of JobAnalysisRequest objects (required)")
+		baseline   = flag.String("baseline", "", "baseline variant, e.g. \"model=gemini-2.0-flash-exp,prompt=v1\"")
+		candidate  = flag.String("candidate", "", "optional candidate variant compared against the baseline")
+		useJudge   = flag.Bool("judge", false, "score outputs with the LLM-judge rubric")
+		judgeModel = flag.String("judge-model", gemini.DefaultModel, "model used by the LLM judge")
+		limit      = flag.Int("limit", 0, "only evaluate the first N cases (0 = all)")
+		format     = flag.String("format", "markdown", "report format: markdown or json")
+		outPath    = flag.String("out", "", "write the report to this file instead of stdout")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s -corpus FILE [flags]\n\nPrompt versions: %s\n\n",
+			os.Args[0], strings.Join(gemini.PromptVersions(), ", "))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*corpusPath, *baseline, *candidate, *useJudge, *judgeModel, *limit, *format, *outPath); err != nil {
+		log.Fatalf("eval: %v", err)
+	}
+}
+
+func run(corpusPath, baselineSpec, candidateSpec string, useJudge bool, judgeModel string, limit int, format, outPath string) error {
+	if corpusPath == "" {
+		flag.Usage()
+		return fmt.Errorf("-corpus is required")
+	}
+	if format != "markdown" && format != "json" {
+		return fmt.Errorf("unknown report format %q", format)
+	}
+
+	cases, err := eval.LoadCorpus(corpusPath)
+	if err != nil {
+		return err
+	}
+	if limit > 0 && limit < len(cases) {
+		cases = cases[:limit]
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	runner := &eval.Runner{Checks: eval.DefaultChecks(eval.DefaultLimits)}
+	if useJudge {
+		judge, err := gemini.New(gemini.WithAPIKey(os.Getenv("GEMINI_API_KEY")), gemini.WithModel(judgeModel))
+		if err != nil {
+			return fmt.Errorf("failed to create judge: %w", err)
+		}
+		defer judge.Close()
+		runner.Judge = eval.NewJudge(judge)
+	}
+
+	baselineRun, err := runVariant(ctx, runner, baselineSpec, cases)
+	if err != nil {
+		return err
+	}
+	var candidateRun *eval.Run
+	if candidateSpec != "" {
+		if candidateRun, err = runVariant(ctx, runner, candidateSpec, cases); err != nil {
+			return err
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	if outPath != "" {
+		file, err := os.Create(outPath)
+		if err != nil {
+			return fmt.Errorf("failed to create report file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	report := eval.Compare(baselineRun, candidateRun)
+	if format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	return report.WriteMarkdown(out)
+}
+
+func runVariant(ctx context.Context, runner *eval.Runner, spec string, cases []eval.Case) (*eval.Run, error) {
+	variant, err := eval.ParseVariant(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := gemini.New(gemini.WithAPIKey(os.Getenv("GEMINI_API_KEY")),
+		gemini.WithModel(variant.Model), gemini.WithPromptVersion(variant.PromptVersion))
+	if err != nil {
+		return nil, fmt.Errorf("variant %s: %w", variant.Name, err)
+	}
+	defer provider.Close()
+
+	log.Printf("eval: running %d cases with %s", len(cases), variant.Name)
+	return runner.Run(ctx, variant, provider, cases), nil
+}
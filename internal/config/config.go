@@ -0,0 +1,205 @@
+// Package config loads the settings of the API server into a typed struct.
+// Values are layered, each source overriding the previous one:
+//
+//  1. the defaults returned by Default
+//  2. a YAML (.yaml, .yml) or TOML (.toml) file named by -config or CONFIG_FILE
+//  3. environment variables, also read from a .env file
+//  4. command line flags such as -server.port=8080
+//
+// Every setting has a file key, an environment variable and a flag, declared
+// once in the struct tags below. Load validates the result and reports all
+// problems at once.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/joho/godotenv/autoload"
+)
+
+// minAdminKeyLength keeps the bootstrap admin key out of brute-force reach
+const minAdminKeyLength = 32
+
+// Config holds every setting of the API server
+type Config struct {
+	Server    Server    `yaml:"server" toml:"server"`
+	Database  Database  `yaml:"database" toml:"database"`
+	Gemini    Gemini    `yaml:"gemini" toml:"gemini"`
+	Auth      Auth      `yaml:"auth" toml:"auth"`
+	CORS      CORS      `yaml:"cors" toml:"cors"`
+	RateLimit RateLimit `yaml:"rate_limit" toml:"rate_limit"`
+	Log       Log       `yaml:"log" toml:"log"`
+}
+
+// Server configures the HTTP listener
+type Server struct {
+	Port int    `yaml:"port" toml:"port" env:"PORT" help:"HTTP port to listen on"`
+	Env  string `yaml:"env" toml:"env" env:"APP_ENV" help:"deployment environment, e.g. local or production"`
+	// ShutdownTimeout is how long shutdown waits for running analyses
+	// before cancelling them
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" help:"time in-flight requests get to finish on shutdown"`
+	// RequestTimeout bounds profile, admin and other quick routes;
+	// AnalyzeTimeout bounds job analysis, which waits for the LLM
+	RequestTimeout time.Duration `yaml:"request_timeout" toml:"request_timeout" env:"REQUEST_TIMEOUT" help:"deadline of requests other than job analysis"`
+	AnalyzeTimeout time.Duration `yaml:"analyze_timeout" toml:"analyze_timeout" env:"ANALYZE_TIMEOUT" help:"deadline of a job analysis request, including LLM calls"`
+}
+
+// Database configures the PostgreSQL connection
+type Database struct {
+	Host     string `yaml:"host" toml:"host" env:"APP_DB_HOST" help:"PostgreSQL host"`
+	Port     int    `yaml:"port" toml:"port" env:"APP_DB_PORT" help:"PostgreSQL port"`
+	Username string `yaml:"username" toml:"username" env:"APP_DB_USERNAME" help:"PostgreSQL user"`
+	Password string `yaml:"password" toml:"password" env:"APP_DB_PASSWORD" help:"PostgreSQL password"`
+	Database string `yaml:"database" toml:"database" env:"APP_DB_DATABASE" help:"PostgreSQL database name"`
+}
+
+// DSN returns the connection URL of the database
+func (d Database) DSN() string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(d.Username, d.Password),
+		Host:     net.JoinHostPort(d.Host, strconv.Itoa(d.Port)),
+		Path:     "/" + d.Database,
+		RawQuery: "sslmode=disable&search_path=public",
+	}
+	return u.String()
+}
+
+// Gemini configures the LLM provider
+type Gemini struct {
+	APIKey string `yaml:"api_key" toml:"api_key" env:"GEMINI_API_KEY" help:"Gemini API key; analysis is disabled without one"`
+	// Model and PromptVersion fall back to the provider defaults when empty
+	Model         string `yaml:"model" toml:"model" env:"GEMINI_MODEL" help:"Gemini model (default: provider default)"`
+	PromptVersion string `yaml:"prompt_version" toml:"prompt_version" env:"GEMINI_PROMPT_VERSION" help:"prompt version (default: provider default)"`
+	Required      bool   `yaml:"required" toml:"required" env:"GEMINI_REQUIRED" help:"refuse to start when the API key is missing or rejected"`
+	AutoContinue  int    `yaml:"auto_continue" toml:"auto_continue" env:"GEMINI_AUTO_CONTINUE" help:"times a truncated answer is resumed before failing"`
+}
+
+// Auth configures API key authentication
+type Auth struct {
+	AdminAPIKey string `yaml:"admin_api_key" toml:"admin_api_key" env:"ADMIN_API_KEY" help:"bootstrap key for the admin endpoints (at least 32 characters)"`
+}
+
+// CORS configures the browser origins allowed to call the API
+type CORS struct {
+	AllowedOrigins   []string `yaml:"allowed_origins" toml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS" help:"comma separated allowed origins, * for any"`
+	AllowCredentials bool     `yaml:"allow_credentials" toml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS" help:"send Access-Control-Allow-Credentials"`
+}
+
+// RateLimit configures per-client limits; 0 disables a limit
+type RateLimit struct {
+	AnalyzePerMinute  int `yaml:"analyze_per_minute" toml:"analyze_per_minute" env:"RATE_LIMIT_ANALYZE_PER_MINUTE" help:"sustained analyses per minute per API key"`
+	AnalyzeBurst      int `yaml:"analyze_burst" toml:"analyze_burst" env:"RATE_LIMIT_ANALYZE_BURST" help:"analysis burst size per API key"`
+	AnalyzeConcurrent int `yaml:"analyze_concurrent" toml:"analyze_concurrent" env:"RATE_LIMIT_ANALYZE_CONCURRENT" help:"analyses running at once per API key"`
+	ProfilePerMinute  int `yaml:"profile_per_minute" toml:"profile_per_minute" env:"RATE_LIMIT_PROFILE_PER_MINUTE" help:"profile and job tracking requests per minute per API key"`
+	ProfileBurst      int `yaml:"profile_burst" toml:"profile_burst" env:"RATE_LIMIT_PROFILE_BURST" help:"profile and job tracking request burst size per API key"`
+	AnalyzeDailyQuota int `yaml:"analyze_daily_quota" toml:"analyze_daily_quota" env:"ANALYZE_DAILY_QUOTA" help:"analyses per API key per UTC day"`
+	IPPerMinute       int `yaml:"ip_per_minute" toml:"ip_per_minute" env:"RATE_LIMIT_IP_PER_MINUTE" help:"API requests per minute per client IP, checked before the API key"`
+	IPBurst           int `yaml:"ip_burst" toml:"ip_burst" env:"RATE_LIMIT_IP_BURST" help:"API request burst size per client IP"`
+}
+
+// Log configures the process logger
+type Log struct {
+	Level  string `yaml:"level" toml:"level" env:"LOG_LEVEL" help:"minimum level: debug, info, warn or error"`
+	Format string `yaml:"format" toml:"format" env:"LOG_FORMAT" help:"json or text"`
+}
+
+// Default returns the settings used when no source overrides them
+func Default() Config {
+	return Config{
+		// An analysis with auto-continue can take close to a minute
+		Server: Server{
+			Port:            8080,
+			Env:             "local",
+			ShutdownTimeout: time.Minute,
+			RequestTimeout:  10 * time.Second,
+			AnalyzeTimeout:  90 * time.Second,
+		},
+		Database: Database{Host: "localhost", Port: 5432},
+		Gemini:   Gemini{AutoContinue: 1},
+		// The Upwork site is where the bookmarklet runs
+		CORS: CORS{AllowedOrigins: []string{"https://www.upwork.com"}},
+		// A Gemini analysis takes several seconds and costs tokens, so
+		// clients are held to a handful per minute. Profile calls are cheap
+		// database reads and writes.
+		RateLimit: RateLimit{
+			AnalyzePerMinute:  6,
+			AnalyzeBurst:      3,
+			AnalyzeConcurrent: 2,
+			ProfilePerMinute:  60,
+			ProfileBurst:      20,
+			AnalyzeDailyQuota: 200,
+			IPPerMinute:       120,
+			IPBurst:           60,
+		},
+		Log: Log{Level: "info", Format: "json"},
+	}
+}
+
+// Validate reports every invalid setting, naming its file key and
+// environment variable
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(validPort(c.Server.Port), "server.port (PORT) must be between 1 and 65535, got %d", c.Server.Port)
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout (SHUTDOWN_TIMEOUT) must be positive, got %s", c.Server.ShutdownTimeout)
+	check(c.Server.RequestTimeout > 0, "server.request_timeout (REQUEST_TIMEOUT) must be positive, got %s", c.Server.RequestTimeout)
+	check(c.Server.AnalyzeTimeout > 0, "server.analyze_timeout (ANALYZE_TIMEOUT) must be positive, got %s", c.Server.AnalyzeTimeout)
+
+	check(c.Database.Host != "", "database.host (APP_DB_HOST) is required")
+	check(validPort(c.Database.Port), "database.port (APP_DB_PORT) must be between 1 and 65535, got %d", c.Database.Port)
+	check(c.Database.Username != "", "database.username (APP_DB_USERNAME) is required")
+	check(c.Database.Database != "", "database.database (APP_DB_DATABASE) is required")
+
+	check(!c.Gemini.Required || c.Gemini.APIKey != "",
+		"gemini.api_key (GEMINI_API_KEY) is required when gemini.required (GEMINI_REQUIRED) is true")
+	check(c.Gemini.AutoContinue >= 0, "gemini.auto_continue (GEMINI_AUTO_CONTINUE) must not be negative, got %d", c.Gemini.AutoContinue)
+
+	check(c.Auth.AdminAPIKey == "" || len(c.Auth.AdminAPIKey) >= minAdminKeyLength,
+		"auth.admin_api_key (ADMIN_API_KEY) must be at least %d characters, got %d", minAdminKeyLength, len(c.Auth.AdminAPIKey))
+
+	limits := []struct {
+		name  string
+		value int
+	}{
+		{"rate_limit.analyze_per_minute (RATE_LIMIT_ANALYZE_PER_MINUTE)", c.RateLimit.AnalyzePerMinute},
+		{"rate_limit.analyze_burst (RATE_LIMIT_ANALYZE_BURST)", c.RateLimit.AnalyzeBurst},
+		{"rate_limit.analyze_concurrent (RATE_LIMIT_ANALYZE_CONCURRENT)", c.RateLimit.AnalyzeConcurrent},
+		{"rate_limit.profile_per_minute (RATE_LIMIT_PROFILE_PER_MINUTE)", c.RateLimit.ProfilePerMinute},
+		{"rate_limit.profile_burst (RATE_LIMIT_PROFILE_BURST)", c.RateLimit.ProfileBurst},
+		{"rate_limit.analyze_daily_quota (ANALYZE_DAILY_QUOTA)", c.RateLimit.AnalyzeDailyQuota},
+		{"rate_limit.ip_per_minute (RATE_LIMIT_IP_PER_MINUTE)", c.RateLimit.IPPerMinute},
+		{"rate_limit.ip_burst (RATE_LIMIT_IP_BURST)", c.RateLimit.IPBurst},
+	}
+	for _, limit := range limits {
+		check(limit.value >= 0, "%s must not be negative, got %d", limit.name, limit.value)
+	}
+
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level (LOG_LEVEL) must be debug, info, warn or error, got %q", c.Log.Level))
+	}
+	switch strings.ToLower(c.Log.Format) {
+	case "json", "text":
+	default:
+		errs = append(errs, fmt.Errorf("log.format (LOG_FORMAT) must be json or text, got %q", c.Log.Format))
+	}
+
+	return errors.Join(errs...)
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"go.yaml.in/yaml/v3"
+)
+
+// Load builds the configuration from the defaults, the config file, the
+// environment and the command line flags in args, and validates it. The
+// returned error lists every problem found. flag.ErrHelp is returned when
+// args ask for -help.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+	settings := settingsOf(&cfg)
+
+	fs := flag.NewFlagSet("upwork-buddy", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML config file (env CONFIG_FILE)")
+	for _, s := range settings {
+		fs.String(s.key, "", s.help+" (env "+s.env+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	if *configFile != "" {
+		if err := loadFile(&cfg, *configFile); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range settings {
+		if raw, ok := os.LookupEnv(s.env); ok {
+			if err := s.set(raw); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			}
+		}
+	}
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.key == f.Name {
+				if err := s.set(f.Value.String()); err != nil {
+					errs = append(errs, fmt.Errorf("-%s: %w", s.key, err))
+				}
+			}
+		}
+	})
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return &cfg, nil
+}
+
+// loadFile decodes a YAML or TOML file into cfg, chosen by extension.
+// Unknown keys are rejected so typos do not go unnoticed.
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		// An empty file decodes to io.EOF and leaves the defaults in place
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("invalid config file %s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("config file %s must end in .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// setting is one leaf field of Config together with its names
+type setting struct {
+	// key is the dotted file key, also used as the flag name
+	key   string
+	env   string
+	help  string
+	value reflect.Value
+}
+
+// settingsOf lists the fields of cfg that carry an env tag
+func settingsOf(cfg *Config) []setting {
+	var settings []setting
+	root := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < root.NumField(); i++ {
+		section := root.Type().Field(i)
+		group := root.Field(i)
+		for j := 0; j < group.NumField(); j++ {
+			field := group.Type().Field(j)
+			env := field.Tag.Get("env")
+			if env == "" {
+				continue
+			}
+			settings = append(settings, setting{
+				key:   section.Tag.Get("yaml") + "." + field.Tag.Get("yaml"),
+				env:   env,
+				help:  field.Tag.Get("help"),
+				value: group.Field(j),
+			})
+		}
+	}
+	return settings
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// set parses raw into the field. Lists are comma separated.
+func (s setting) set(raw string) error {
+	raw = strings.TrimSpace(raw)
+	if raw == "" && s.value.Kind() != reflect.String && s.value.Kind() != reflect.Slice {
+		// An empty number or switch, such as PORT= in a .env file, keeps
+		// the current value
+		return nil
+	}
+	switch {
+	case s.value.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, use a value such as 30s or 1h", raw)
+		}
+		s.value.SetInt(int64(d))
+	case s.value.Kind() == reflect.String:
+		s.value.SetString(raw)
+	case s.value.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		s.value.SetInt(int64(n))
+	case s.value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q, use true or false", raw)
+		}
+		s.value.SetBool(b)
+	case s.value.Kind() == reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		s.value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", s.value.Type())
+	}
+	return nil
+}
@@ -0,0 +1,4 @@
+-- Modify "users" table
+ALTER TABLE "public"."users" ADD COLUMN "is_admin" boolean NOT NULL DEFAULT false, ADD COLUMN "api_key_hash" text NULL, ADD COLUMN "api_key_prefix" text NULL, ADD COLUMN "api_key_created_at" timestamp(3) NULL, ADD COLUMN "api_key_revoked_at" timestamp(3) NULL;
+-- Create index "idx_users_api_key_hash" to table: "users"
+CREATE UNIQUE INDEX "idx_users_api_key_hash" ON "public"."users" ("api_key_hash");
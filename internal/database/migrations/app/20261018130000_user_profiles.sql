@@ -0,0 +1,27 @@
+-- Create "profiles" table
+CREATE TABLE "public"."profiles" (
+  "id" bigserial NOT NULL,
+  "user_id" bigint NOT NULL,
+  "description" text NULL,
+  "skills" text NULL,
+  "created_at" timestamp(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updated_at" timestamp(3) NOT NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_profiles_user" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_profiles_user_id" to table: "profiles"
+CREATE UNIQUE INDEX "idx_profiles_user_id" ON "public"."profiles" ("user_id");
+-- Create "portfolio_items" table
+CREATE TABLE "public"."portfolio_items" (
+  "id" bigserial NOT NULL,
+  "profile_id" bigint NULL,
+  "title" text NULL,
+  "link" text NULL,
+  "description" text NULL,
+  "created_at" timestamp(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updated_at" timestamp(3) NOT NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_profiles_portfolio_items" FOREIGN KEY ("profile_id") REFERENCES "public"."profiles" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_portfolio_items_profile_id" to table: "portfolio_items"
+CREATE INDEX "idx_portfolio_items_profile_id" ON "public"."portfolio_items" ("profile_id");
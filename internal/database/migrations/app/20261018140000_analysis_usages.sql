@@ -0,0 +1,9 @@
+-- Create "analysis_usages" table
+CREATE TABLE "public"."analysis_usages" (
+  "user_id" bigint NOT NULL,
+  "day" date NOT NULL,
+  "count" bigint NOT NULL DEFAULT 0,
+  "updated_at" timestamp(3) NOT NULL,
+  PRIMARY KEY ("user_id", "day"),
+  CONSTRAINT "fk_analysis_usages_user" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
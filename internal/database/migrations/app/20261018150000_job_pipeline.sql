@@ -0,0 +1,23 @@
+-- Map the free-text statuses of existing jobs onto the pipeline stages
+UPDATE "public"."jobs" SET "status" = CASE
+  WHEN lower("status") IN ('discovered', 'analyzed', 'shortlisted', 'applied', 'viewed', 'interviewing', 'hired', 'declined', 'archived') THEN lower("status")
+  WHEN lower("status") = 'rejected' THEN 'declined'
+  ELSE 'discovered'
+END;
+-- Modify "jobs" table
+ALTER TABLE "public"."jobs" ALTER COLUMN "status" SET NOT NULL, ALTER COLUMN "status" SET DEFAULT 'discovered';
+-- Create "job_transitions" table
+CREATE TABLE "public"."job_transitions" (
+  "id" bigserial NOT NULL,
+  "job_id" bigint NOT NULL,
+  "from_status" text NULL,
+  "to_status" text NOT NULL,
+  "note" text NULL,
+  "created_at" timestamp(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_job_transitions_job" FOREIGN KEY ("job_id") REFERENCES "public"."jobs" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_job_transitions_job_id" to table: "job_transitions"
+CREATE INDEX "idx_job_transitions_job_id" ON "public"."job_transitions" ("job_id");
+-- Start the history of existing jobs at their current stage
+INSERT INTO "public"."job_transitions" ("job_id", "to_status", "created_at") SELECT "id", "status", "updated_at" FROM "public"."jobs";
@@ -0,0 +1,6 @@
+-- Modify "portfolio_items" table
+ALTER TABLE "public"."portfolio_items" ADD COLUMN "position" bigint NOT NULL DEFAULT 0;
+-- Keep the current order, which followed the IDs
+UPDATE "public"."portfolio_items" AS "p" SET "position" = "r"."position"
+FROM (SELECT "id", row_number() OVER (PARTITION BY "profile_id" ORDER BY "id") - 1 AS "position" FROM "public"."portfolio_items") AS "r"
+WHERE "p"."id" = "r"."id";
@@ -0,0 +1,4 @@
+-- Modify "profiles" table
+ALTER TABLE "public"."profiles" ADD COLUMN "version" bigint NOT NULL DEFAULT 0;
+-- Stored profiles start at 1; 0 stands for a profile that was never saved
+UPDATE "public"."profiles" SET "version" = 1;
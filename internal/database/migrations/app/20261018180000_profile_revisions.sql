@@ -0,0 +1,54 @@
+-- Create "profile_revisions" table
+CREATE TABLE "public"."profile_revisions" (
+  "id" bigserial NOT NULL,
+  "profile_id" bigint NOT NULL,
+  "version" bigint NOT NULL,
+  "description" text NULL,
+  "skills" text NULL,
+  "restored_from_id" bigint NULL,
+  "created_at" timestamp(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_profile_revisions_profile" FOREIGN KEY ("profile_id") REFERENCES "public"."profiles" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "fk_profile_revisions_restored_from" FOREIGN KEY ("restored_from_id") REFERENCES "public"."profile_revisions" ("id") ON UPDATE NO ACTION ON DELETE SET NULL
+);
+-- Create index "idx_profile_revisions_restored_from_id" to table: "profile_revisions"
+CREATE INDEX "idx_profile_revisions_restored_from_id" ON "public"."profile_revisions" ("restored_from_id");
+-- Create index "idx_profile_revisions_version" to table: "profile_revisions"
+CREATE UNIQUE INDEX "idx_profile_revisions_version" ON "public"."profile_revisions" ("profile_id", "version");
+-- Create "profile_revision_items" table
+CREATE TABLE "public"."profile_revision_items" (
+  "id" bigserial NOT NULL,
+  "revision_id" bigint NOT NULL,
+  "item_id" bigint NOT NULL,
+  "title" text NULL,
+  "link" text NULL,
+  "description" text NULL,
+  "position" bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_profile_revisions_portfolio_items" FOREIGN KEY ("revision_id") REFERENCES "public"."profile_revisions" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_profile_revision_items_revision_id" to table: "profile_revision_items"
+CREATE INDEX "idx_profile_revision_items_revision_id" ON "public"."profile_revision_items" ("revision_id");
+-- Create "analyses" table
+CREATE TABLE "public"."analyses" (
+  "id" bigserial NOT NULL,
+  "user_id" bigint NOT NULL,
+  "profile_revision_id" bigint NULL,
+  "job_title" text NOT NULL,
+  "result" jsonb NOT NULL,
+  "partial" boolean NOT NULL DEFAULT false,
+  "created_at" timestamp(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_analyses_profile_revision" FOREIGN KEY ("profile_revision_id") REFERENCES "public"."profile_revisions" ("id") ON UPDATE NO ACTION ON DELETE SET NULL,
+  CONSTRAINT "fk_analyses_user" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_analyses_profile_revision_id" to table: "analyses"
+CREATE INDEX "idx_analyses_profile_revision_id" ON "public"."analyses" ("profile_revision_id");
+-- Create index "idx_analyses_user_id" to table: "analyses"
+CREATE INDEX "idx_analyses_user_id" ON "public"."analyses" ("user_id");
+-- Start the history of saved profiles at their current version
+INSERT INTO "public"."profile_revisions" ("profile_id", "version", "description", "skills", "created_at")
+SELECT "id", "version", "description", "skills", "updated_at" FROM "public"."profiles" WHERE "version" > 0;
+INSERT INTO "public"."profile_revision_items" ("revision_id", "item_id", "title", "link", "description", "position")
+SELECT "r"."id", "i"."id", "i"."title", "i"."link", "i"."description", "i"."position"
+FROM "public"."portfolio_items" AS "i" JOIN "public"."profile_revisions" AS "r" ON "r"."profile_id" = "i"."profile_id";
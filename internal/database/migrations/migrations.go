@@ -0,0 +1,26 @@
+// Package migrations embeds the versioned Atlas migrations so the server can
+// tell whether the database schema is up to date.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+	"strings"
+)
+
+//go:embed app/*.sql
+var app embed.FS
+
+// AppVersions returns the versions of the app migrations, oldest first. The
+// version is the timestamp prefix of the file name, as recorded by Atlas in
+// atlas_schema_revisions.
+func AppVersions() []string {
+	// The pattern is fixed and the files are embedded, so this cannot fail
+	names, _ := fs.Glob(app, "app/*.sql")
+	versions := make([]string, 0, len(names))
+	for _, name := range names {
+		version, _, _ := strings.Cut(strings.TrimPrefix(name, "app/"), "_")
+		versions = append(versions, version)
+	}
+	return versions
+}
@@ -5,22 +5,30 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"os"
+	"log/slog"
 	"strconv"
 	"time"
 
+	"upwork-buddy/internal/config"
+	"upwork-buddy/internal/database/migrations"
+
 	_ "github.com/jackc/pgx/v5/stdlib"
-	_ "github.com/joho/godotenv/autoload"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
+	gormtracing "gorm.io/plugin/opentelemetry/tracing"
 )
 
 // Service represents a service that interacts with a database.
 type DatabaseService interface {
 	// Health returns a map of health status information.
 	// The keys and values in the map are service-specific.
-	Health() map[string]string
+	Health(ctx context.Context) map[string]string
+
+	// PendingMigrations returns the versions of the embedded migrations
+	// that have not been fully applied to the database
+	PendingMigrations(ctx context.Context) ([]string, error)
 
 	// Close terminates the database connection.
 	// It returns an error if the connection cannot be closed.
@@ -36,27 +44,20 @@ type DatabaseService interface {
 type service struct {
 	db   *sql.DB
 	gorm *gorm.DB
+	name string
 }
 
-var (
-	database   = os.Getenv("APP_DB_DATABASE")
-	password   = os.Getenv("APP_DB_PASSWORD")
-	username   = os.Getenv("APP_DB_USERNAME")
-	port       = os.Getenv("APP_DB_PORT")
-	host       = os.Getenv("APP_DB_HOST")
-	dbInstance *service
-)
+var dbInstance *service
 
-func New() DatabaseService {
+// New connects to the database described by cfg. The connection is opened
+// once and shared by later calls.
+func New(cfg config.Database) DatabaseService {
 	// Reuse Connection
 	if dbInstance != nil {
 		return dbInstance
 	}
 
-	connStr := fmt.Sprintf(
-		"postgres://%s:%s@%s:%s/%s?sslmode=disable&search_path=public",
-		username, password, host, port, database,
-	)
+	connStr := cfg.DSN()
 
 	// Initialize SQL database
 	db, err := sql.Open("pgx", connStr)
@@ -68,23 +69,53 @@ func New() DatabaseService {
 	db.SetMaxIdleConns(50)
 	db.SetMaxOpenConns(50)
 
-	// Initialize GORM with the same connection string
-	gormDB, err := gorm.Open(postgres.Open(connStr), &gorm.Config{
+	// Initialize GORM on the same pool, so the pool settings, metrics and
+	// Close cover both
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{
 		NamingStrategy: schema.NamingStrategy{
 			TablePrefix: "public.",
 		},
+		// Map driver errors such as unique violations to gorm.ErrDuplicatedKey
+		TranslateError: true,
+		Logger:         newGormLogger(),
+		// Start even while the database is down; /readyz reports it until
+		// the connection comes back
+		DisableAutomaticPing: true,
 	})
 	if err != nil {
 		log.Fatal("Failed to connect to database with GORM:", err)
 	}
+	// Queries become child spans of the request; parameters are left out for
+	// the same reason they are left out of the logs
+	if err := gormDB.Use(gormtracing.NewPlugin(gormtracing.WithoutMetrics(), gormtracing.WithoutQueryVariables())); err != nil {
+		log.Fatal("Failed to install GORM tracing:", err)
+	}
 
 	dbInstance = &service{
 		db:   db,
 		gorm: gormDB,
+		name: cfg.Database,
 	}
 	return dbInstance
 }
 
+// newGormLogger sends GORM logs to the default slog logger so they carry the
+// request ID of the context passed to WithContext. Queries are logged without
+// their parameters, which can hold profile text and API key hashes. Every
+// query is logged at debug level; otherwise only slow and failed ones.
+func newGormLogger() logger.Interface {
+	level := logger.Warn
+	if slog.Default().Enabled(context.Background(), slog.LevelDebug) {
+		level = logger.Info
+	}
+	return logger.NewSlogLogger(slog.Default(), logger.Config{
+		SlowThreshold:             200 * time.Millisecond,
+		LogLevel:                  level,
+		IgnoreRecordNotFoundError: true,
+		ParameterizedQueries:      true,
+	})
+}
+
 // GetDB returns the raw SQL database connection
 func (s *service) GetDB() *sql.DB {
 	return s.db
@@ -95,12 +126,9 @@ func (s *service) GetGorm() *gorm.DB {
 	return s.gorm
 }
 
-// Health checks the health of the database connection by pinging the database.
-// It returns a map with keys indicating various health statistics.
-func (s *service) Health() map[string]string {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
-	defer cancel()
-
+// Health pings the database and reports connection pool statistics. A failed
+// ping is reported with status "down"; it never stops the process.
+func (s *service) Health(ctx context.Context) map[string]string {
 	stats := make(map[string]string)
 
 	// Ping the database
@@ -108,7 +136,7 @@ func (s *service) Health() map[string]string {
 	if err != nil {
 		stats["status"] = "down"
 		stats["error"] = fmt.Sprintf("db down: %v", err)
-		log.Fatalf("db down: %v", err)
+		slog.WarnContext(ctx, "database ping failed", "error", err)
 		return stats
 	}
 
@@ -144,11 +172,42 @@ func (s *service) Health() map[string]string {
 	return stats
 }
 
+// PendingMigrations compares the embedded migration files with the
+// revisions Atlas recorded in the atlas_schema_revisions table
+func (s *service) PendingMigrations(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT version FROM "atlas_schema_revisions"."atlas_schema_revisions" WHERE applied >= total`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read atlas_schema_revisions: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[string]bool{}
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to read atlas_schema_revisions: %w", err)
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read atlas_schema_revisions: %w", err)
+	}
+
+	var pending []string
+	for _, version := range migrations.AppVersions() {
+		if !applied[version] {
+			pending = append(pending, version)
+		}
+	}
+	return pending, nil
+}
+
 // Close closes the database connection.
 // It logs a message indicating the disconnection from the specific database.
 // If the connection is successfully closed, it returns nil.
 // If an error occurs while closing the connection, it returns the error.
 func (s *service) Close() error {
-	log.Printf("Disconnected from database: %s", database)
+	slog.Info("disconnected from database", "database", s.name)
 	return s.db.Close()
 }
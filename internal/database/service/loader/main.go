@@ -13,6 +13,13 @@ func main() {
 	stmts, err := gormschema.New("postgres").Load(
 		&service.User{},
 		&service.Job{},
+		&service.JobTransition{},
+		&service.Profile{},
+		&service.PortfolioItem{},
+		&service.ProfileRevision{},
+		&service.ProfileRevisionItem{},
+		&service.Analysis{},
+		&service.AnalysisUsage{},
 	)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to load gorm schema: %v\n", err)
@@ -6,13 +6,20 @@ import (
 	_ "ariga.io/atlas-provider-gorm/gormschema"
 )
 
-// Example model - User represents a user in the system
+// User represents an API client. Each user holds at most one active API key,
+// stored only as a SHA-256 hash; APIKeyPrefix keeps the first characters so
+// admins can tell keys apart.
 type User struct {
-	ID        uint      `gorm:"primaryKey;autoIncrement"`
-	Email     string    `gorm:"type:text;not null;uniqueIndex"`
-	Name      string    `gorm:"type:text;not null"`
-	CreatedAt time.Time `gorm:"type:timestamp(3);default:CURRENT_TIMESTAMP;not null"`
-	UpdatedAt time.Time `gorm:"type:timestamp(3);not null"`
+	ID              uint       `gorm:"primaryKey;autoIncrement"`
+	Email           string     `gorm:"type:text;not null;uniqueIndex"`
+	Name            string     `gorm:"type:text;not null"`
+	IsAdmin         bool       `gorm:"not null;default:false"`
+	APIKeyHash      *string    `gorm:"type:text;uniqueIndex"`
+	APIKeyPrefix    string     `gorm:"type:text"`
+	APIKeyCreatedAt *time.Time `gorm:"type:timestamp(3)"`
+	APIKeyRevokedAt *time.Time `gorm:"type:timestamp(3)"`
+	CreatedAt       time.Time  `gorm:"type:timestamp(3);default:CURRENT_TIMESTAMP;not null"`
+	UpdatedAt       time.Time  `gorm:"type:timestamp(3);not null"`
 }
 
 // Example model - Job represents a job posting or opportunity
@@ -21,8 +28,8 @@ type Job struct {
 	Title       string     `gorm:"type:text;not null"`
 	Description string     `gorm:"type:text"`
 	URL         string     `gorm:"type:text"`
-	Source      string     `gorm:"type:text"` // e.g., "upwork", "linkedin", etc.
-	Status      string     `gorm:"type:text"` // e.g., "new", "applied", "rejected", etc.
+	Source      string     `gorm:"type:text"`                             // e.g., "upwork", "linkedin", etc.
+	Status      string     `gorm:"type:text;not null;default:discovered"` // pipeline stage, see JobTransition
 	UserID      *uint      `gorm:"index"`
 	User        *User      `gorm:"foreignKey:UserID"`
 	CreatedAt   time.Time  `gorm:"type:timestamp(3);default:CURRENT_TIMESTAMP;not null"`
@@ -30,23 +37,94 @@ type Job struct {
 	AppliedAt   *time.Time `gorm:"type:timestamp(3)"`
 }
 
-// Profile stores customizable freelancer metadata used during analysis
+// JobTransition records a job moving to a pipeline stage. FromStatus is nil
+// for the stage a job was created in.
+type JobTransition struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	JobID      uint      `gorm:"not null;index"`
+	Job        *Job      `gorm:"foreignKey:JobID;constraint:OnDelete:CASCADE"`
+	FromStatus *string   `gorm:"type:text"`
+	ToStatus   string    `gorm:"type:text;not null"`
+	Note       string    `gorm:"type:text"`
+	CreatedAt  time.Time `gorm:"type:timestamp(3);default:CURRENT_TIMESTAMP;not null"`
+}
+
+// Profile stores customizable freelancer metadata used during analysis. Each
+// user has at most one profile. Version counts the saves of the profile and
+// its items and backs the profile ETag.
 type Profile struct {
 	ID             uint            `gorm:"primaryKey;autoIncrement"`
+	UserID         uint            `gorm:"not null;uniqueIndex"`
+	User           *User           `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 	Description    string          `gorm:"type:text"`
 	Skills         string          `gorm:"type:text"`
-	PortfolioItems []PortfolioItem `gorm:"foreignKey:ProfileID"`
+	PortfolioItems []PortfolioItem `gorm:"foreignKey:ProfileID;constraint:OnDelete:CASCADE"`
+	Version        int             `gorm:"not null;default:0"`
 	CreatedAt      time.Time       `gorm:"type:timestamp(3);default:CURRENT_TIMESTAMP;not null"`
 	UpdatedAt      time.Time       `gorm:"type:timestamp(3);not null"`
 }
 
-// PortfolioItem represents a single portfolio entry
+// PortfolioItem represents a single portfolio entry. Items are listed by
+// Position, then ID.
 type PortfolioItem struct {
 	ID          uint      `gorm:"primaryKey;autoIncrement"`
 	ProfileID   uint      `gorm:"index"`
 	Title       string    `gorm:"type:text"`
 	Link        string    `gorm:"type:text"`
 	Description string    `gorm:"type:text"`
+	Position    int       `gorm:"not null;default:0"`
 	CreatedAt   time.Time `gorm:"type:timestamp(3);default:CURRENT_TIMESTAMP;not null"`
 	UpdatedAt   time.Time `gorm:"type:timestamp(3);not null"`
 }
+
+// ProfileRevision is an immutable copy of a profile and its portfolio items,
+// taken at every change. Version is the profile version it captured;
+// RestoredFromID names the revision a restore copied.
+type ProfileRevision struct {
+	ID             uint                  `gorm:"primaryKey;autoIncrement"`
+	ProfileID      uint                  `gorm:"not null;uniqueIndex:idx_profile_revisions_version"`
+	Profile        *Profile              `gorm:"foreignKey:ProfileID;constraint:OnDelete:CASCADE"`
+	Version        int                   `gorm:"not null;uniqueIndex:idx_profile_revisions_version"`
+	Description    string                `gorm:"type:text"`
+	Skills         string                `gorm:"type:text"`
+	PortfolioItems []ProfileRevisionItem `gorm:"foreignKey:RevisionID;constraint:OnDelete:CASCADE"`
+	RestoredFromID *uint                 `gorm:"index"`
+	RestoredFrom   *ProfileRevision      `gorm:"foreignKey:RestoredFromID;constraint:OnDelete:SET NULL"`
+	CreatedAt      time.Time             `gorm:"type:timestamp(3);default:CURRENT_TIMESTAMP;not null"`
+}
+
+// ProfileRevisionItem is a portfolio item as a revision captured it. ItemID
+// is the id the item had, which may since have been deleted.
+type ProfileRevisionItem struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	RevisionID  uint   `gorm:"not null;index"`
+	ItemID      uint   `gorm:"not null"`
+	Title       string `gorm:"type:text"`
+	Link        string `gorm:"type:text"`
+	Description string `gorm:"type:text"`
+	Position    int    `gorm:"not null;default:0"`
+}
+
+// Analysis records a completed job analysis. ProfileRevisionID names the
+// stored profile it used; it is nil when the request carried its own profile.
+type Analysis struct {
+	ID                uint             `gorm:"primaryKey;autoIncrement"`
+	UserID            uint             `gorm:"not null;index"`
+	User              *User            `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	ProfileRevisionID *uint            `gorm:"index"`
+	ProfileRevision   *ProfileRevision `gorm:"foreignKey:ProfileRevisionID;constraint:OnDelete:SET NULL"`
+	JobTitle          string           `gorm:"type:text;not null"`
+	Result            string           `gorm:"type:jsonb;not null"` // the JobAnalysisResponse as returned
+	Partial           bool             `gorm:"not null;default:false"`
+	CreatedAt         time.Time        `gorm:"type:timestamp(3);default:CURRENT_TIMESTAMP;not null"`
+}
+
+// AnalysisUsage counts the job analyses a user has requested on one UTC day.
+// It backs the daily analysis quota.
+type AnalysisUsage struct {
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false"`
+	User      *User     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Day       time.Time `gorm:"primaryKey;type:date"`
+	Count     int       `gorm:"not null;default:0"`
+	UpdatedAt time.Time `gorm:"type:timestamp(3);not null"`
+}
@@ -0,0 +1,153 @@
+package eval
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"upwork-buddy/internal/gemini"
+)
+
+// CheckResult is the outcome of one deterministic check on one output
+type CheckResult struct {
+	Name   string  `json:"name"`
+	Passed bool    `json:"passed"`
+	Score  float64 `json:"score"`
+	Detail string  `json:"detail,omitempty"`
+}
+
+// Check scores a single aspect of an analysis without calling a model
+type Check func(c Case, analysis *gemini.Analysis) CheckResult
+
+// Limits bounds the size of the generated sections. They mirror the ranges
+// requested by the analysis prompt.
+type Limits struct {
+	MinProposalWords int
+	MaxProposalWords int
+	MinQuestions     int
+	MaxQuestions     int
+	MinTips          int
+	MaxTips          int
+}
+
+// DefaultLimits matches the section sizes asked for in the prompt
+var DefaultLimits = Limits{
+	MinProposalWords: 80,
+	MaxProposalWords: 450,
+	MinQuestions:     5,
+	MaxQuestions:     7,
+	MinTips:          4,
+	MaxTips:          6,
+}
+
+// MinSkillCoverage is the fraction of the job's required skills that must be
+// mentioned in the proposal for the skill coverage check to pass
+const MinSkillCoverage = 0.5
+
+// DefaultChecks returns the standard deterministic check suite
+func DefaultChecks(limits Limits) []Check {
+	return []Check{
+		checkValidJSON,
+		checkLengthLimits(limits),
+		checkSkillCoverage,
+		checkNoPlaceholders,
+	}
+}
+
+func checkValidJSON(_ Case, analysis *gemini.Analysis) CheckResult {
+	if analysis.ParseError != nil {
+		return CheckResult{Name: "valid_json", Detail: analysis.ParseError.Error()}
+	}
+	if analysis.Response.Partial {
+		return CheckResult{Name: "valid_json", Score: 0.5, Detail: "only a partial analysis could be recovered"}
+	}
+	return CheckResult{Name: "valid_json", Passed: true, Score: 1}
+}
+
+func checkLengthLimits(limits Limits) Check {
+	return func(_ Case, analysis *gemini.Analysis) CheckResult {
+		resp := analysis.Response
+		var problems []string
+
+		words := len(strings.Fields(resp.Proposal))
+		if words < limits.MinProposalWords || words > limits.MaxProposalWords {
+			problems = append(problems, fmt.Sprintf("proposal has %d words (want %d-%d)",
+				words, limits.MinProposalWords, limits.MaxProposalWords))
+		}
+		if n := len(resp.QuestionsForClient); n < limits.MinQuestions || n > limits.MaxQuestions {
+			problems = append(problems, fmt.Sprintf("%d questions (want %d-%d)",
+				n, limits.MinQuestions, limits.MaxQuestions))
+		}
+		if n := len(resp.TipsAndAdvice); n < limits.MinTips || n > limits.MaxTips {
+			problems = append(problems, fmt.Sprintf("%d tips (want %d-%d)",
+				n, limits.MinTips, limits.MaxTips))
+		}
+
+		return CheckResult{
+			Name:   "length_limits",
+			Passed: len(problems) == 0,
+			Score:  1 - float64(len(problems))/3,
+			Detail: strings.Join(problems, "; "),
+		}
+	}
+}
+
+func checkSkillCoverage(c Case, analysis *gemini.Analysis) CheckResult {
+	skills := splitSkills(c.Skills)
+	if len(skills) == 0 {
+		return CheckResult{Name: "skill_coverage", Passed: true, Score: 1, Detail: "job lists no skills"}
+	}
+
+	proposal := strings.ToLower(analysis.Response.Proposal)
+	var missing []string
+	for _, skill := range skills {
+		if !strings.Contains(proposal, strings.ToLower(skill)) {
+			missing = append(missing, skill)
+		}
+	}
+
+	coverage := float64(len(skills)-len(missing)) / float64(len(skills))
+	result := CheckResult{
+		Name:   "skill_coverage",
+		Passed: coverage >= MinSkillCoverage,
+		Score:  coverage,
+	}
+	if len(missing) > 0 {
+		result.Detail = "missing: " + strings.Join(missing, ", ")
+	}
+	return result
+}
+
+// placeholderPattern matches template leftovers such as "[Your Name]",
+// "{{client}}" or "<insert link>"
+var placeholderPattern = regexp.MustCompile(`(?i)\[(your|client|insert|company|name)[^\]]*\]|\{\{[^}]*\}\}|<\s*insert[^>]*>|\bTODO\b|\bTBD\b|lorem ipsum|\bXXX+\b`)
+
+func checkNoPlaceholders(_ Case, analysis *gemini.Analysis) CheckResult {
+	resp := analysis.Response
+	fields := append([]string{resp.Proposal, resp.SpecSheetPrompt, resp.ToneAnalysis}, resp.QuestionsForClient...)
+	fields = append(fields, resp.TipsAndAdvice...)
+
+	var found []string
+	for _, field := range fields {
+		found = append(found, placeholderPattern.FindAllString(field, -1)...)
+	}
+	if len(found) > 0 {
+		return CheckResult{Name: "no_placeholders", Detail: "found: " + strings.Join(found, ", ")}
+	}
+	return CheckResult{Name: "no_placeholders", Passed: true, Score: 1}
+}
+
+// splitSkills turns a comma, semicolon or newline separated skill list into
+// trimmed, non-empty entries
+func splitSkills(skills string) []string {
+	parts := strings.FieldsFunc(skills, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n'
+	})
+	result := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
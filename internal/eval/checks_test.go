@@ -0,0 +1,51 @@
+package eval
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"upwork-buddy/internal/gemini"
+)
+
+func TestDefaultChecks(t *testing.T) {
+	c := Case{ID: "case", JobAnalysisRequest: gemini.JobAnalysisRequest{Skills: "Go, PostgreSQL, Docker"}}
+	good := &gemini.Analysis{Response: &gemini.JobAnalysisResponse{
+		Proposal:           strings.Repeat("word ", 100) + "I have shipped Go services on PostgreSQL.",
+		QuestionsForClient: []string{"1", "2", "3", "4", "5"},
+		TipsAndAdvice:      []string{"1", "2", "3", "4"},
+	}}
+	bad := &gemini.Analysis{
+		ParseError: errors.New("invalid character"),
+		Response: &gemini.JobAnalysisResponse{
+			Proposal: "Hi [Your Name] here, I know Docker.",
+		},
+	}
+
+	checks := DefaultChecks(DefaultLimits)
+	for _, check := range checks {
+		if result := check(c, good); !result.Passed {
+			t.Errorf("%s: expected good analysis to pass, got %q", result.Name, result.Detail)
+		}
+		if result := check(c, bad); result.Passed {
+			t.Errorf("%s: expected bad analysis to fail", result.Name)
+		}
+	}
+}
+
+func TestCompareReportsRegressions(t *testing.T) {
+	baseline := &Run{Variant: Variant{Name: "a"}, Results: []CaseResult{
+		{CaseID: "1", Checks: []CheckResult{{Name: "valid_json", Passed: true, Score: 1}}},
+	}}
+	candidate := &Run{Variant: Variant{Name: "b"}, Results: []CaseResult{
+		{CaseID: "1", Checks: []CheckResult{{Name: "valid_json"}}},
+	}}
+
+	report := Compare(baseline, candidate)
+	if len(report.Diffs) != 1 || len(report.Diffs[0].Regressed) != 1 {
+		t.Fatalf("expected one regression, got %+v", report.Diffs)
+	}
+	if report.Baseline.PassRate["valid_json"] != 1 || report.Candidate.PassRate["valid_json"] != 0 {
+		t.Errorf("unexpected pass rates: %v / %v", report.Baseline.PassRate, report.Candidate.PassRate)
+	}
+}
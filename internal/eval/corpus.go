@@ -0,0 +1,60 @@
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"upwork-buddy/internal/gemini"
+)
+
+// Case is a single recorded job analysis request from the corpus
+type Case struct {
+	ID string `json:"id,omitempty"`
+	gemini.JobAnalysisRequest
+}
+
+// LoadCorpus reads a JSONL file where every non-empty line is a
+// JobAnalysisRequest, optionally carrying an "id" field. Lines without an id
+// are named after their line number.
+func LoadCorpus(path string) ([]Case, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open corpus: %w", err)
+	}
+	defer file.Close()
+
+	var cases []Case
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		var c Case
+		if err := json.Unmarshal([]byte(line), &c); err != nil {
+			return nil, fmt.Errorf("corpus line %d: %w", lineNo, err)
+		}
+		if strings.TrimSpace(c.JobTitle) == "" && strings.TrimSpace(c.JobDescription) == "" {
+			return nil, fmt.Errorf("corpus line %d: job_title and job_description are both empty", lineNo)
+		}
+		if c.ID == "" {
+			c.ID = "line-" + strconv.Itoa(lineNo)
+		}
+		cases = append(cases, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read corpus: %w", err)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("corpus %s contains no cases", path)
+	}
+	return cases, nil
+}
@@ -0,0 +1,91 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"upwork-buddy/internal/gemini"
+)
+
+// TextGenerator is the subset of the LLM provider used by the judge
+type TextGenerator interface {
+	GenerateText(ctx context.Context, prompt string) (string, error)
+}
+
+// JudgeResult holds rubric scores from 1 (poor) to 5 (excellent)
+type JudgeResult struct {
+	Relevance   int    `json:"relevance"`
+	Specificity int    `json:"specificity"`
+	Tone        int    `json:"tone"`
+	Actionable  int    `json:"actionable"`
+	Rationale   string `json:"rationale"`
+}
+
+// Mean returns the average of the rubric scores
+func (j JudgeResult) Mean() float64 {
+	return float64(j.Relevance+j.Specificity+j.Tone+j.Actionable) / 4
+}
+
+// Judge scores an analysis against a fixed rubric using an LLM
+type Judge struct {
+	model TextGenerator
+}
+
+// NewJudge creates a judge backed by the given model
+func NewJudge(model TextGenerator) *Judge {
+	return &Judge{model: model}
+}
+
+// Score asks the judge model to grade the analysis produced for a case
+func (j *Judge) Score(ctx context.Context, c Case, resp *gemini.JobAnalysisResponse) (*JudgeResult, error) {
+	output, err := json.MarshalIndent(resp, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode analysis for judge: %w", err)
+	}
+
+	text, err := j.model.GenerateText(ctx, fmt.Sprintf(judgePrompt,
+		c.JobTitle, c.JobDescription, c.Budget, c.Skills, c.UserSkills, output))
+	if err != nil {
+		return nil, fmt.Errorf("judge call failed: %w", err)
+	}
+
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+
+	var result JudgeResult
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &result); err != nil {
+		return nil, fmt.Errorf("failed to decode judge verdict: %w", err)
+	}
+	for _, score := range []int{result.Relevance, result.Specificity, result.Tone, result.Actionable} {
+		if score < 1 || score > 5 {
+			return nil, fmt.Errorf("judge returned out-of-range score %d", score)
+		}
+	}
+	return &result, nil
+}
+
+const judgePrompt = `You are grading an AI-generated Upwork proposal package. Be strict and consistent.
+
+JOB POSTING:
+Title: %s
+Description: %s
+Budget: %s
+Required Skills: %s
+
+CONTRACTOR SKILLS: %s
+
+GENERATED ANALYSIS:
+%s
+
+Score each criterion from 1 (poor) to 5 (excellent):
+- relevance: the proposal addresses this specific job rather than a generic one
+- specificity: concrete details, deliverables and estimates instead of vague claims
+- tone: matches the formality of the posting and reads as written by a human
+- actionable: questions, tips and spec sheet prompt are directly usable
+
+Respond with JSON only:
+{"relevance": 0, "specificity": 0, "tone": 0, "actionable": 0, "rationale": "one or two sentences"}`
@@ -0,0 +1,227 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Summary aggregates a run into comparable numbers
+type Summary struct {
+	Variant       Variant            `json:"variant"`
+	Cases         int                `json:"cases"`
+	Errors        int                `json:"errors"`
+	PassRate      map[string]float64 `json:"pass_rate"`
+	MeanScore     map[string]float64 `json:"mean_score"`
+	AllPassedRate float64            `json:"all_passed_rate"`
+	JudgeMean     float64            `json:"judge_mean,omitempty"`
+	JudgedCases   int                `json:"judged_cases"`
+	MeanLatencyMS float64            `json:"mean_latency_ms"`
+}
+
+// CaseDiff describes how one case changed between the baseline and candidate
+type CaseDiff struct {
+	CaseID     string   `json:"case_id"`
+	Regressed  []string `json:"regressed,omitempty"`
+	Improved   []string `json:"improved,omitempty"`
+	JudgeDelta float64  `json:"judge_delta,omitempty"`
+	Note       string   `json:"note,omitempty"`
+}
+
+// Report compares a candidate run against a baseline run
+type Report struct {
+	Baseline  Summary    `json:"baseline"`
+	Candidate *Summary   `json:"candidate,omitempty"`
+	Diffs     []CaseDiff `json:"diffs,omitempty"`
+	Runs      []*Run     `json:"runs"`
+}
+
+// Summarize aggregates the results of a single run
+func Summarize(run *Run) Summary {
+	s := Summary{
+		Variant:   run.Variant,
+		Cases:     len(run.Results),
+		PassRate:  map[string]float64{},
+		MeanScore: map[string]float64{},
+	}
+
+	var scored, allPassed int
+	var latency int64
+	var judgeTotal float64
+	for _, result := range run.Results {
+		if result.Error != "" {
+			s.Errors++
+			continue
+		}
+		scored++
+		latency += result.LatencyMS
+
+		passedAll := true
+		for _, check := range result.Checks {
+			passed := 0.0
+			if check.Passed {
+				passed = 1
+			} else {
+				passedAll = false
+			}
+			s.PassRate[check.Name] += passed
+			s.MeanScore[check.Name] += check.Score
+		}
+		if passedAll {
+			allPassed++
+		}
+		if result.Judge != nil {
+			s.JudgedCases++
+			judgeTotal += result.Judge.Mean()
+		}
+	}
+
+	if scored > 0 {
+		for name := range s.PassRate {
+			s.PassRate[name] /= float64(scored)
+			s.MeanScore[name] /= float64(scored)
+		}
+		s.AllPassedRate = float64(allPassed) / float64(scored)
+		s.MeanLatencyMS = float64(latency) / float64(scored)
+	}
+	if s.JudgedCases > 0 {
+		s.JudgeMean = judgeTotal / float64(s.JudgedCases)
+	}
+	return s
+}
+
+// Compare builds a report for a baseline run and an optional candidate run
+func Compare(baseline, candidate *Run) *Report {
+	report := &Report{Baseline: Summarize(baseline), Runs: []*Run{baseline}}
+	if candidate == nil {
+		return report
+	}
+
+	summary := Summarize(candidate)
+	report.Candidate = &summary
+	report.Runs = append(report.Runs, candidate)
+
+	byID := make(map[string]CaseResult, len(candidate.Results))
+	for _, result := range candidate.Results {
+		byID[result.CaseID] = result
+	}
+
+	for _, before := range baseline.Results {
+		after, ok := byID[before.CaseID]
+		if !ok {
+			continue
+		}
+		diff := CaseDiff{CaseID: before.CaseID}
+		switch {
+		case before.Error != "" && after.Error == "":
+			diff.Note = "baseline failed, candidate succeeded"
+		case before.Error == "" && after.Error != "":
+			diff.Note = "candidate failed: " + after.Error
+		case before.Error == "" && after.Error == "":
+			for _, check := range before.Checks {
+				switch {
+				case check.Passed && !after.Passed(check.Name):
+					diff.Regressed = append(diff.Regressed, check.Name)
+				case !check.Passed && after.Passed(check.Name):
+					diff.Improved = append(diff.Improved, check.Name)
+				}
+			}
+			if before.Judge != nil && after.Judge != nil {
+				diff.JudgeDelta = after.Judge.Mean() - before.Judge.Mean()
+			}
+		}
+		if diff.Note != "" || len(diff.Regressed) > 0 || len(diff.Improved) > 0 || diff.JudgeDelta != 0 {
+			report.Diffs = append(report.Diffs, diff)
+		}
+	}
+	return report
+}
+
+// WriteMarkdown renders the report as a markdown document
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	summaries := []Summary{r.Baseline}
+	if r.Candidate != nil {
+		summaries = append(summaries, *r.Candidate)
+	}
+
+	b.WriteString("# Upwork Buddy eval report\n\n")
+	b.WriteString("| metric |")
+	for _, s := range summaries {
+		fmt.Fprintf(&b, " %s |", s.Variant.Name)
+	}
+	b.WriteString("\n|---|")
+	for range summaries {
+		b.WriteString("---|")
+	}
+	b.WriteString("\n")
+
+	row := func(label string, value func(Summary) string) {
+		fmt.Fprintf(&b, "| %s |", label)
+		for _, s := range summaries {
+			fmt.Fprintf(&b, " %s |", value(s))
+		}
+		b.WriteString("\n")
+	}
+	row("cases", func(s Summary) string { return fmt.Sprintf("%d", s.Cases) })
+	row("provider errors", func(s Summary) string { return fmt.Sprintf("%d", s.Errors) })
+	row("all checks passed", func(s Summary) string { return percent(s.AllPassedRate) })
+	for _, name := range checkNames(summaries) {
+		row(name, func(s Summary) string {
+			return fmt.Sprintf("%s (score %.2f)", percent(s.PassRate[name]), s.MeanScore[name])
+		})
+	}
+	row("judge mean (1-5)", func(s Summary) string {
+		if s.JudgedCases == 0 {
+			return "n/a"
+		}
+		return fmt.Sprintf("%.2f (%d cases)", s.JudgeMean, s.JudgedCases)
+	})
+	row("mean latency", func(s Summary) string { return fmt.Sprintf("%.0f ms", s.MeanLatencyMS) })
+
+	if r.Candidate != nil {
+		b.WriteString("\n## Per-case changes\n\n")
+		if len(r.Diffs) == 0 {
+			b.WriteString("No differences between variants.\n")
+		}
+		for _, d := range r.Diffs {
+			fmt.Fprintf(&b, "- **%s**", d.CaseID)
+			if len(d.Regressed) > 0 {
+				fmt.Fprintf(&b, " regressed: %s;", strings.Join(d.Regressed, ", "))
+			}
+			if len(d.Improved) > 0 {
+				fmt.Fprintf(&b, " improved: %s;", strings.Join(d.Improved, ", "))
+			}
+			if d.JudgeDelta != 0 {
+				fmt.Fprintf(&b, " judge %+.2f;", d.JudgeDelta)
+			}
+			if d.Note != "" {
+				fmt.Fprintf(&b, " %s", d.Note)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func checkNames(summaries []Summary) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, s := range summaries {
+		for name := range s.PassRate {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func percent(v float64) string {
+	return fmt.Sprintf("%.0f%%", v*100)
+}
@@ -0,0 +1,133 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"upwork-buddy/internal/gemini"
+)
+
+// Analyzer is the provider call replayed for every corpus case
+type Analyzer interface {
+	AnalyzeJobDetailed(ctx context.Context, req gemini.JobAnalysisRequest) (*gemini.Analysis, error)
+}
+
+// Variant identifies one provider configuration under evaluation
+type Variant struct {
+	Name          string `json:"name"`
+	Provider      string `json:"provider"`
+	Model         string `json:"model"`
+	PromptVersion string `json:"prompt_version"`
+}
+
+// ParseVariant reads a variant from a comma separated key=value list such as
+// "model=gemini-2.0-flash,prompt=v2". Unset keys fall back to the defaults.
+func ParseVariant(spec string) (Variant, error) {
+	v := Variant{
+		Provider:      "gemini",
+		Model:         gemini.DefaultModel,
+		PromptVersion: gemini.DefaultPromptVersion,
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return v, fmt.Errorf("invalid variant setting %q, expected key=value", pair)
+		}
+		switch strings.TrimSpace(key) {
+		case "name":
+			v.Name = strings.TrimSpace(value)
+		case "provider":
+			v.Provider = strings.TrimSpace(value)
+		case "model":
+			v.Model = strings.TrimSpace(value)
+		case "prompt":
+			v.PromptVersion = strings.TrimSpace(value)
+		default:
+			return v, fmt.Errorf("unknown variant setting %q", key)
+		}
+	}
+	if v.Provider != "gemini" {
+		return v, fmt.Errorf("unsupported provider %q", v.Provider)
+	}
+	if v.Name == "" {
+		v.Name = v.Model + "/" + v.PromptVersion
+	}
+	return v, nil
+}
+
+// CaseResult holds the scores for one case under one variant
+type CaseResult struct {
+	CaseID    string        `json:"case_id"`
+	Error     string        `json:"error,omitempty"`
+	Checks    []CheckResult `json:"checks,omitempty"`
+	Judge     *JudgeResult  `json:"judge,omitempty"`
+	JudgeErr  string        `json:"judge_error,omitempty"`
+	LatencyMS int64         `json:"latency_ms"`
+}
+
+// Passed reports whether the named check passed for this case
+func (r CaseResult) Passed(check string) bool {
+	for _, c := range r.Checks {
+		if c.Name == check {
+			return c.Passed
+		}
+	}
+	return false
+}
+
+// Run is the full set of results for one variant
+type Run struct {
+	Variant Variant      `json:"variant"`
+	Results []CaseResult `json:"results"`
+}
+
+// Runner replays a corpus through an analyzer and scores every output
+type Runner struct {
+	Checks []Check
+	Judge  *Judge
+}
+
+// Run evaluates every case with the given analyzer. Provider failures are
+// recorded on the case rather than aborting the run.
+func (r *Runner) Run(ctx context.Context, variant Variant, analyzer Analyzer, cases []Case) *Run {
+	run := &Run{Variant: variant, Results: make([]CaseResult, 0, len(cases))}
+	for i, c := range cases {
+		if ctx.Err() != nil {
+			break
+		}
+		log.Printf("eval: [%s] case %d/%d %s", variant.Name, i+1, len(cases), c.ID)
+		run.Results = append(run.Results, r.runCase(ctx, analyzer, c))
+	}
+	return run
+}
+
+func (r *Runner) runCase(ctx context.Context, analyzer Analyzer, c Case) CaseResult {
+	result := CaseResult{CaseID: c.ID}
+
+	analysis, err := analyzer.AnalyzeJobDetailed(ctx, c.JobAnalysisRequest)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.LatencyMS = analysis.Latency.Milliseconds()
+
+	for _, check := range r.Checks {
+		result.Checks = append(result.Checks, check(c, analysis))
+	}
+
+	if r.Judge != nil && analysis.ParseError == nil {
+		verdict, err := r.Judge.Score(ctx, c, analysis.Response)
+		if err != nil {
+			result.JudgeErr = err.Error()
+		} else {
+			result.Judge = verdict
+		}
+	}
+	return result
+}
@@ -0,0 +1,150 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// Sentinel errors describing why a response could not be used. Match them
+// with errors.Is; the concrete error is a *ResponseError.
+var (
+	// ErrBlocked means the prompt or the output was stopped by safety filters
+	ErrBlocked = errors.New("response blocked by safety filters")
+	// ErrTruncated means the output hit the token limit before completing
+	ErrTruncated = errors.New("response truncated at max tokens")
+	// ErrEmpty means the model returned no usable text
+	ErrEmpty = errors.New("response contained no text")
+	// ErrRecitation means the output was stopped for reciting source material
+	ErrRecitation = errors.New("response stopped for recitation")
+)
+
+// ResponseError carries the details of an unusable model response
+type ResponseError struct {
+	// Kind is one of ErrBlocked, ErrTruncated, ErrEmpty or ErrRecitation
+	Kind error
+	// Reason is the raw finish or block reason reported by the API
+	Reason string
+	// Categories lists the safety categories that triggered a block
+	Categories []string
+	// Partial holds any text generated before the response was cut off
+	Partial string
+}
+
+func (e *ResponseError) Error() string {
+	msg := e.Kind.Error()
+	if e.Reason != "" {
+		msg += fmt.Sprintf(" (reason=%s)", e.Reason)
+	}
+	if len(e.Categories) > 0 {
+		msg += fmt.Sprintf(" (categories=%s)", strings.Join(e.Categories, ","))
+	}
+	return msg
+}
+
+func (e *ResponseError) Unwrap() error {
+	return e.Kind
+}
+
+// candidateText returns the text of the first candidate, or a *ResponseError
+// when the prompt was blocked or the candidate did not finish normally
+func candidateText(resp *genai.GenerateContentResponse) (string, error) {
+	if resp == nil {
+		return "", &ResponseError{Kind: ErrEmpty}
+	}
+
+	if len(resp.Candidates) == 0 {
+		if fb := resp.PromptFeedback; fb != nil && fb.BlockReason != "" {
+			return "", &ResponseError{
+				Kind:       ErrBlocked,
+				Reason:     string(fb.BlockReason),
+				Categories: blockedCategories(fb.SafetyRatings),
+			}
+		}
+		return "", &ResponseError{Kind: ErrEmpty}
+	}
+
+	// Only the first candidate is used: concatenating alternatives would mix
+	// two different answers into one proposal
+	candidate := resp.Candidates[0]
+	var text strings.Builder
+	if candidate.Content != nil {
+		for _, part := range candidate.Content.Parts {
+			if part.Text != "" && !part.Thought {
+				text.WriteString(part.Text)
+			}
+		}
+	}
+
+	switch candidate.FinishReason {
+	case genai.FinishReasonSafety, genai.FinishReasonBlocklist, genai.FinishReasonProhibitedContent,
+		genai.FinishReasonSPII, genai.FinishReasonLanguage:
+		return "", &ResponseError{
+			Kind:       ErrBlocked,
+			Reason:     string(candidate.FinishReason),
+			Categories: blockedCategories(candidate.SafetyRatings),
+		}
+	case genai.FinishReasonRecitation:
+		return "", &ResponseError{Kind: ErrRecitation, Reason: string(candidate.FinishReason)}
+	case genai.FinishReasonMaxTokens:
+		return text.String(), &ResponseError{Kind: ErrTruncated, Reason: string(candidate.FinishReason), Partial: text.String()}
+	}
+
+	if strings.TrimSpace(text.String()) == "" {
+		return "", &ResponseError{Kind: ErrEmpty, Reason: string(candidate.FinishReason)}
+	}
+	return text.String(), nil
+}
+
+// blockedCategories lists the harm categories that were blocked or rated high
+func blockedCategories(ratings []*genai.SafetyRating) []string {
+	var categories []string
+	for _, rating := range ratings {
+		if rating == nil {
+			continue
+		}
+		if rating.Blocked || rating.Probability == genai.HarmProbabilityHigh {
+			categories = append(categories, string(rating.Category))
+		}
+	}
+	return categories
+}
+
+// ErrorClass groups an error returned by the Service into a small set of
+// labels for metrics: blocked, truncated, empty, recitation, timeout,
+// canceled, rate_limited, server, client or network
+func ErrorClass(err error) string {
+	var apiErr genai.APIError
+	switch {
+	case errors.Is(err, ErrBlocked):
+		return "blocked"
+	case errors.Is(err, ErrTruncated):
+		return "truncated"
+	case errors.Is(err, ErrEmpty):
+		return "empty"
+	case errors.Is(err, ErrRecitation):
+		return "recitation"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &apiErr):
+		return statusClass(apiErr.Code)
+	default:
+		return "network"
+	}
+}
+
+func statusClass(code int) string {
+	switch {
+	case code == 429:
+		return "rate_limited"
+	case code >= 500:
+		return "server"
+	default:
+		return "client"
+	}
+}
@@ -0,0 +1,142 @@
+package gemini
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DefaultPromptVersion is the prompt used when no version is requested.
+const DefaultPromptVersion = "v1"
+
+// promptBuilder renders the analysis prompt for a single request
+type promptBuilder func(req JobAnalysisRequest) string
+
+// prompts holds every prompt version that can be selected by name. Versions
+// are kept side by side so they can be compared with the offline eval harness.
+var prompts = map[string]promptBuilder{
+	"v1": buildPromptV1,
+	"v2": buildPromptV2,
+}
+
+// PromptVersions returns the names of all registered prompt versions
+func PromptVersions() []string {
+	versions := make([]string, 0, len(prompts))
+	for version := range prompts {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// BuildPrompt renders the prompt for the given version
+func BuildPrompt(version string, req JobAnalysisRequest) (string, error) {
+	build, ok := prompts[version]
+	if !ok {
+		return "", fmt.Errorf("unknown prompt version %q", version)
+	}
+	return build(req), nil
+}
+
+// analysisTask is the shared body of every prompt version: the job, the
+// contractor and the sections to write. It takes the job title, description,
+// budget and skills, then the contractor profile and skills.
+const analysisTask = `You are an expert freelance consultant helping contractors on Upwork create winning proposals and project plans.
+
+JOB POSTING:
+Title: %s
+Description: %s
+Budget: %s
+Required Skills: %s
+
+CONTRACTOR PROFILE:
+Profile: %s
+Skills: %s
+
+Please provide a comprehensive analysis with the following sections:
+
+1. PROPOSAL (2-3 paragraphs)
+Write a compelling, professional yet relatable proposal that:
+- Demonstrates understanding of the project requirements
+- Highlights relevant experience and skills
+- Shows enthusiasm and reliability
+- Uses a tone that matches the job posting's formality level
+
+2. SPEC SHEET PROMPT
+Create a detailed prompt that can be used with AI coding agents (GitHub Copilot, Jules, etc.) to generate a technical specification document. This prompt should include:
+- Project requirements breakdown
+- Technical architecture considerations
+- Implementation approach
+- Key deliverables
+- Testing and QA requirements
+
+3. TIME ESTIMATE
+Provide a realistic time estimate broken down by:
+- Total hours required
+- Breakdown by major project phases
+- Buffer time for revisions and feedback
+
+4. WORKLOAD DIVISION
+Suggest how to divide work between:
+- AI agents (GitHub Copilot, Jules): tasks suitable for automation, code generation, repetitive work
+- Human contractor: tasks requiring judgment, creative decisions, client communication, QA, strategic planning
+Include specific percentages and reasoning.
+
+5. QUESTIONS FOR CLIENT (5-7 questions)
+List strategic questions to ask the client to:
+- Clarify requirements
+- Understand their goals and priorities
+- Set proper expectations
+- Establish a smooth workflow
+
+6. TIPS AND ADVICE (4-6 points)
+Provide actionable advice on:
+- Setting clear deliverables and milestones
+- Managing client expectations
+- QA and testing approach
+- Handoff procedures
+- Communication best practices
+
+7. TONE ANALYSIS
+Analyze the job posting's tone (formal, casual, technical, etc.) and suggest the best communication approach.`
+
+// analysisFormat lists the JSON keys the response is parsed from
+const analysisFormat = `Format your response as JSON with these exact keys:
+{
+  "proposal": "...",
+  "spec_sheet_prompt": "...",
+  "time_estimate": "...",
+  "workload_division": "...",
+  "questions_for_client": ["...", "..."],
+  "tips_and_advice": ["...", "..."],
+  "tone_analysis": "..."
+}`
+
+// strictOutputRules is the output contract v2 adds to v1
+const strictOutputRules = `STRICT OUTPUT RULES:
+- Respond with a single JSON object and nothing else: no markdown fences, no commentary before or after
+- Never use placeholders such as [Your Name], [Client Name] or <insert ...>; write complete sentences
+- Mention at least half of the job's required skills by name in the proposal when the contractor has them
+- Keep the proposal under 350 words`
+
+// buildPromptV1 constructs the original comprehensive prompt for the AI
+func buildPromptV1(req JobAnalysisRequest) string {
+	return renderTask(req) + "\n\n" + analysisFormat
+}
+
+// buildPromptV2 tightens the output contract of v1: strict JSON only, no
+// placeholders and explicit coverage of the job's required skills
+func buildPromptV2(req JobAnalysisRequest) string {
+	return renderTask(req) + "\n\n" + strictOutputRules + "\n\n" + analysisFormat
+}
+
+// renderTask fills analysisTask with the request
+func renderTask(req JobAnalysisRequest) string {
+	return fmt.Sprintf(analysisTask,
+		req.JobTitle,
+		req.JobDescription,
+		req.Budget,
+		req.Skills,
+		req.UserProfile,
+		req.UserSkills,
+	)
+}
@@ -0,0 +1,200 @@
+package gemini
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// RecorderMode controls whether a Recorder talks to the network
+type RecorderMode int
+
+const (
+	// ModeReplay serves responses from the fixture file and never touches the network
+	ModeReplay RecorderMode = iota
+	// ModeRecord forwards requests to the real API and captures the responses
+	ModeRecord
+)
+
+// RecorderModeFromEnv returns ModeRecord when GEMINI_RECORD is set to a
+// non-empty value, so fixtures can be refreshed with
+// `GEMINI_RECORD=1 GEMINI_API_KEY=... go test ./...`.
+func RecorderModeFromEnv() RecorderMode {
+	if os.Getenv("GEMINI_RECORD") != "" {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// Interaction is a single recorded HTTP exchange. Request headers are not
+// stored so API keys never end up in fixture files.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest identifies the request that produced a response
+type RecordedRequest struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// RecordedResponse is the captured HTTP response
+type RecordedResponse struct {
+	StatusCode int               `json:"status_code"`
+	Header     map[string]string `json:"header,omitempty"`
+	Body       json.RawMessage   `json:"body"`
+}
+
+// Recorder is an http.RoundTripper that captures Gemini API traffic to a
+// fixture file or replays it offline. Inject it with WithHTTPClient.
+type Recorder struct {
+	path string
+	mode RecorderMode
+	base http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewRecorder creates a recorder backed by the fixture at path. In replay mode
+// the fixture must exist; in record mode it is (re)written by Save.
+func NewRecorder(path string, mode RecorderMode) (*Recorder, error) {
+	r := &Recorder{path: path, mode: mode, base: http.DefaultTransport}
+	if mode == ModeRecord {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	if err := json.Unmarshal(data, &r.interactions); err != nil {
+		return nil, fmt.Errorf("failed to decode fixture %s: %w", path, err)
+	}
+	r.used = make([]bool, len(r.interactions))
+	return r, nil
+}
+
+// Client returns an http.Client that routes every request through the recorder
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, fmt.Errorf("recorder: failed to read request body: %w", err)
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if r.mode == ModeRecord {
+		return r.record(req, body)
+	}
+	return r.replay(req)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("recorder: failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interactions = append(r.interactions, Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			Path:   req.URL.Path,
+			Body:   rawJSON(body),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     map[string]string{"Content-Type": resp.Header.Get("Content-Type")},
+			Body:       rawJSON(respBody),
+		},
+	})
+	return resp, nil
+}
+
+// replay serves the first unused interaction with a matching method and path
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.interactions {
+		if r.used[i] || interaction.Request.Method != req.Method || interaction.Request.Path != req.URL.Path {
+			continue
+		}
+		r.used[i] = true
+
+		header := make(http.Header, len(interaction.Response.Header))
+		for key, value := range interaction.Response.Header {
+			header.Set(key, value)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("recorder: no recorded response for %s %s in %s", req.Method, req.URL.Path, r.path)
+}
+
+// Save writes the captured interactions to the fixture file. It is a no-op in
+// replay mode.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fixture: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0o644)
+}
+
+// rawJSON keeps JSON payloads readable in fixtures and quotes anything else
+func rawJSON(data []byte) json.RawMessage {
+	if len(data) == 0 {
+		return nil
+	}
+	if json.Valid(data) {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, data); err == nil {
+			return buf.Bytes()
+		}
+	}
+	quoted, _ := json.Marshal(string(data))
+	return quoted
+}
@@ -0,0 +1,328 @@
+package gemini
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// repairJSON fixes the defects LLMs commonly introduce into JSON output:
+// prose before or after the object, smart quotes used as delimiters, trailing
+// commas, raw newlines and stray quotes inside strings, and strings or
+// brackets left open by truncation. It reports whether unterminated
+// structures had to be closed, meaning some content was lost.
+func repairJSON(text string) (string, bool) {
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return text, false
+	}
+	text = text[start:]
+	if end := strings.LastIndexAny(text, "}]"); end >= 0 && !looksTruncated(text[end+1:]) {
+		text = text[:end+1]
+	}
+
+	var (
+		out       strings.Builder
+		stack     []byte
+		inString  bool
+		smartOpen bool
+		escaped   bool
+	)
+	out.Grow(len(text) + 16)
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		next := i + size
+
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+				out.WriteString(text[i:next])
+			case r == '\\':
+				escaped = true
+				out.WriteRune(r)
+			case smartOpen && (r == '”' || r == '“'):
+				inString, smartOpen = false, false
+				out.WriteByte('"')
+			case r == '"' && !smartOpen:
+				if closesString(text[next:]) {
+					inString = false
+					out.WriteByte('"')
+				} else {
+					// A quote that does not end the value is part of the text
+					out.WriteString(`\"`)
+				}
+			case r == '"':
+				out.WriteString(`\"`)
+			case r == '\n':
+				out.WriteString(`\n`)
+			case r == '\r':
+				out.WriteString(`\r`)
+			case r == '\t':
+				out.WriteString(`\t`)
+			case r < 0x20:
+				// Other control characters are never valid inside strings
+			default:
+				out.WriteString(text[i:next])
+			}
+			i = next
+			continue
+		}
+
+		switch r {
+		case '"':
+			inString = true
+			out.WriteByte('"')
+		case '“', '”':
+			inString, smartOpen = true, true
+			out.WriteByte('"')
+		case '{', '[':
+			stack = append(stack, byte(r))
+			out.WriteRune(r)
+		case '}', ']':
+			trimTrailingComma(&out)
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			out.WriteRune(r)
+		default:
+			out.WriteString(text[i:next])
+		}
+		i = next
+
+		if len(stack) == 0 && (r == '}' || r == ']') {
+			break
+		}
+	}
+
+	truncated := inString || len(stack) > 0
+	if inString {
+		if escaped {
+			// Drop a dangling backslash so the closing quote is not escaped
+			s := out.String()
+			out.Reset()
+			out.WriteString(s[:len(s)-1])
+		}
+		out.WriteByte('"')
+	}
+	if len(stack) > 0 {
+		closeDanglingValue(&out)
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i] == '{' {
+				out.WriteByte('}')
+			} else {
+				out.WriteByte(']')
+			}
+		}
+	}
+	return out.String(), truncated
+}
+
+// closesString reports whether a quote followed by rest ends a JSON string,
+// i.e. the next significant character is structural or input ends
+func closesString(rest string) bool {
+	rest = strings.TrimLeft(rest, " \t\r\n")
+	if rest == "" {
+		return true
+	}
+	switch rest[0] {
+	case ',', '}', ']', ':':
+		return true
+	}
+	return false
+}
+
+// looksTruncated reports whether text after the last closing bracket still
+// contains JSON-like content rather than trailing prose
+func looksTruncated(tail string) bool {
+	tail = strings.TrimSpace(tail)
+	return strings.HasPrefix(tail, ",") || strings.HasPrefix(tail, `"`)
+}
+
+// trimTrailingComma removes a comma (and surrounding whitespace) that
+// directly precedes a closing bracket
+func trimTrailingComma(out *strings.Builder) {
+	s := strings.TrimRight(out.String(), " \t\r\n")
+	if strings.HasSuffix(s, ",") {
+		out.Reset()
+		out.WriteString(s[:len(s)-1])
+	}
+}
+
+// closeDanglingValue completes a member that was cut off after its key or
+// separator so the object can be closed
+func closeDanglingValue(out *strings.Builder) {
+	s := strings.TrimRight(out.String(), " \t\r\n")
+	switch {
+	case strings.HasSuffix(s, ","):
+		s = s[:len(s)-1]
+	case strings.HasSuffix(s, ":"):
+		s += "null"
+	}
+	out.Reset()
+	out.WriteString(s)
+}
+
+// analysisFields lists the top-level keys of JobAnalysisResponse in the order
+// the prompt asks for them
+var analysisFields = []string{
+	"proposal",
+	"spec_sheet_prompt",
+	"time_estimate",
+	"workload_division",
+	"questions_for_client",
+	"tips_and_advice",
+	"tone_analysis",
+}
+
+var listMarkerPattern = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s*`)
+
+var fieldKeyPattern = regexp.MustCompile(`["“”]([a-z_]+)["“”]\s*:`)
+
+// extractFields recovers whatever sections can be decoded individually from
+// output that is not valid JSON as a whole. It returns false when no section
+// could be recovered.
+func extractFields(text string) (*JobAnalysisResponse, bool) {
+	result := &JobAnalysisResponse{
+		TimeEstimate:       json.RawMessage(`""`),
+		WorkloadDivision:   json.RawMessage(`""`),
+		QuestionsForClient: []string{},
+		TipsAndAdvice:      []string{},
+	}
+
+	// Split the text at every known key so each section can be decoded alone
+	var locs [][]int
+	for _, loc := range fieldKeyPattern.FindAllStringSubmatchIndex(text, -1) {
+		if isAnalysisField(text[loc[2]:loc[3]]) {
+			locs = append(locs, loc)
+		}
+	}
+
+	found := 0
+	for i, loc := range locs {
+		end := len(text)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		value, ok := decodeSection(text[loc[1]:end])
+		if !ok {
+			continue
+		}
+		if assignField(result, text[loc[2]:loc[3]], value) {
+			found++
+		}
+	}
+	return result, found > 0
+}
+
+func isAnalysisField(key string) bool {
+	for _, field := range analysisFields {
+		if field == key {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeSection decodes the value of a single key, repairing it if needed
+func decodeSection(section string) (interface{}, bool) {
+	section = strings.TrimSpace(section)
+	if section == "" {
+		return nil, false
+	}
+
+	// The decoder stops after the first value, ignoring the separator and any
+	// closing brace of the enclosing object
+	var value interface{}
+	if err := json.NewDecoder(strings.NewReader(section)).Decode(&value); err == nil {
+		return value, true
+	}
+
+	section = strings.TrimRight(section, " \t\r\n,")
+	if strings.Count(section, "}") > strings.Count(section, "{") {
+		section = strings.TrimRight(strings.TrimSuffix(section, "}"), " \t\r\n,")
+	}
+	repaired := repairValue(section)
+	if err := json.Unmarshal([]byte(repaired), &value); err == nil {
+		return value, true
+	}
+	return nil, false
+}
+
+// repairValue runs repairJSON on a bare value by wrapping it in an array
+func repairValue(section string) string {
+	repaired, _ := repairJSON("[" + section + "]")
+	var values []json.RawMessage
+	if err := json.Unmarshal([]byte(repaired), &values); err != nil || len(values) == 0 {
+		return section
+	}
+	return string(values[0])
+}
+
+// assignField stores a decoded section on the response, coercing the shapes
+// models commonly confuse (a list returned as one string and vice versa)
+func assignField(result *JobAnalysisResponse, key string, value interface{}) bool {
+	switch key {
+	case "proposal":
+		result.Proposal = asString(value)
+		return result.Proposal != ""
+	case "spec_sheet_prompt":
+		result.SpecSheetPrompt = asString(value)
+		return result.SpecSheetPrompt != ""
+	case "tone_analysis":
+		result.ToneAnalysis = asString(value)
+		return result.ToneAnalysis != ""
+	case "time_estimate", "workload_division":
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return false
+		}
+		if key == "time_estimate" {
+			result.TimeEstimate = raw
+		} else {
+			result.WorkloadDivision = raw
+		}
+		return true
+	case "questions_for_client":
+		result.QuestionsForClient = asStringList(value)
+		return len(result.QuestionsForClient) > 0
+	case "tips_and_advice":
+		result.TipsAndAdvice = asStringList(value)
+		return len(result.TipsAndAdvice) > 0
+	}
+	return false
+}
+
+func asString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		raw, _ := json.Marshal(v)
+		return string(raw)
+	}
+}
+
+func asStringList(value interface{}) []string {
+	var items []string
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s := strings.TrimSpace(asString(item)); s != "" {
+				items = append(items, s)
+			}
+		}
+	case string:
+		for _, line := range strings.Split(v, "\n") {
+			line = strings.TrimSpace(listMarkerPattern.ReplaceAllString(line, ""))
+			if line != "" {
+				items = append(items, line)
+			}
+		}
+	}
+	return items
+}
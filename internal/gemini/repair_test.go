@@ -0,0 +1,104 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestRepairJSON(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		wantProposal  string
+		wantTruncated bool
+	}{
+		{
+			name:         "trailing commas",
+			input:        `{"proposal": "Hi", "tips_and_advice": ["a", "b",],}`,
+			wantProposal: "Hi",
+		},
+		{
+			name:         "smart quotes as delimiters",
+			input:        `{“proposal”: “Hi there”}`,
+			wantProposal: "Hi there",
+		},
+		{
+			name:         "smart quotes inside a string are kept",
+			input:        `{"proposal": "I loved “Go” for years"}`,
+			wantProposal: "I loved “Go” for years",
+		},
+		{
+			name:         "raw newline in string",
+			input:        "{\"proposal\": \"Line one\nLine two\"}",
+			wantProposal: "Line one\nLine two",
+		},
+		{
+			name:         "unescaped inner quote",
+			input:        `{"proposal": "They said "ship it" today"}`,
+			wantProposal: `They said "ship it" today`,
+		},
+		{
+			name:         "prose around the object",
+			input:        "Here is your analysis:\n{\"proposal\": \"Hi\"}\nLet me know if you need anything else!",
+			wantProposal: "Hi",
+		},
+		{
+			name:          "unterminated string from truncation",
+			input:         `{"proposal": "Hi", "tone_analysis": "Formal and`,
+			wantProposal:  "Hi",
+			wantTruncated: true,
+		},
+		{
+			name:          "truncated after key",
+			input:         `{"proposal": "Hi", "questions_for_client": ["One?", "Two?"], "tips_and_advice":`,
+			wantProposal:  "Hi",
+			wantTruncated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repaired, truncated := repairJSON(tt.input)
+			if truncated != tt.wantTruncated {
+				t.Errorf("truncated = %v, want %v", truncated, tt.wantTruncated)
+			}
+
+			var result JobAnalysisResponse
+			if err := json.Unmarshal([]byte(repaired), &result); err != nil {
+				t.Fatalf("repaired JSON is invalid: %v\n%s", err, repaired)
+			}
+			if result.Proposal != tt.wantProposal {
+				t.Errorf("proposal = %q, want %q", result.Proposal, tt.wantProposal)
+			}
+		})
+	}
+}
+
+func TestParseAnalysisTextPartial(t *testing.T) {
+	// questions_for_client is a string instead of a list, which breaks
+	// json.Unmarshal for the whole object
+	text := `{
+  "proposal": "Hello, I can help.",
+  "time_estimate": {"total_hours": 20},
+  "questions_for_client": "1. What is the deadline?\n2. Who hosts it?",
+  "tips_and_advice": ["Set milestones"]
+}`
+
+	result, err := parseAnalysisText(context.Background(), text)
+	if err != nil {
+		t.Fatalf("parseAnalysisText returned error: %v", err)
+	}
+	if !result.Partial {
+		t.Error("expected partial marker")
+	}
+	if result.Proposal != "Hello, I can help." {
+		t.Errorf("proposal = %q", result.Proposal)
+	}
+	if len(result.QuestionsForClient) != 2 || result.QuestionsForClient[0] != "What is the deadline?" {
+		t.Errorf("questions = %q", result.QuestionsForClient)
+	}
+	if string(result.TimeEstimate) != `{"total_hours":20}` {
+		t.Errorf("time estimate = %s", result.TimeEstimate)
+	}
+}
@@ -3,19 +3,36 @@ package gemini
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
+	"net/http"
 	"regexp"
 	"strings"
+	"time"
 
+	"upwork-buddy/internal/metrics"
+	"upwork-buddy/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/genai"
 )
 
+// DefaultModel is the Gemini model used when none is configured
+const DefaultModel = "gemini-2.0-flash-exp"
+
+// parseFailureMessage is placed in SpecSheetPrompt when the model output could
+// not be decoded as JSON
+const parseFailureMessage = "Failed to parse response. Please check the API server logs."
+
 // Service handles interactions with Google's Gemini AI
 type Service struct {
-	client *genai.Client
-	model  string
+	client        *genai.Client
+	httpClient    *http.Client
+	model         string
+	promptVersion string
+	autoContinue  int
 }
 
 // JobAnalysisRequest contains the job posting and user profile
@@ -37,237 +54,396 @@ type JobAnalysisResponse struct {
 	QuestionsForClient []string        `json:"questions_for_client"`
 	TipsAndAdvice      []string        `json:"tips_and_advice"`
 	ToneAnalysis       string          `json:"tone_analysis"`
+	// Partial is set when the output had to be repaired and some sections may
+	// be missing or cut short
+	Partial bool `json:"partial,omitempty"`
+}
+
+// Analysis is the detailed outcome of a single AnalyzeJob call, including the
+// raw model output. It is used by tooling such as the offline eval harness.
+type Analysis struct {
+	Response      *JobAnalysisResponse
+	RawText       string
+	ParseError    error
+	Model         string
+	PromptVersion string
+	Latency       time.Duration
+}
+
+// Option customizes a Service created by New
+type Option func(*options)
+
+type options struct {
+	apiKey        string
+	model         string
+	promptVersion string
+	httpClient    *http.Client
+	autoContinue  int
+}
+
+// WithAPIKey sets the Gemini API key; New fails without one
+func WithAPIKey(apiKey string) Option {
+	return func(o *options) { o.apiKey = apiKey }
+}
+
+// WithModel selects the Gemini model used for generation
+func WithModel(model string) Option {
+	return func(o *options) { o.model = model }
+}
+
+// WithPromptVersion selects one of the registered prompt versions
+func WithPromptVersion(version string) Option {
+	return func(o *options) { o.promptVersion = version }
+}
+
+// WithHTTPClient routes API traffic through the given client, e.g. one backed
+// by a Recorder for offline tests
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) { o.httpClient = client }
+}
+
+// WithAutoContinue asks the model to continue a MAX_TOKENS-truncated answer
+// up to n times before giving up with ErrTruncated
+func WithAutoContinue(n int) Option {
+	return func(o *options) { o.autoContinue = n }
 }
 
 // New creates a new Gemini service instance
-func New() (*Service, error) {
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("GEMINI_API_KEY environment variable not set")
+func New(opts ...Option) (*Service, error) {
+	o := options{
+		model:         DefaultModel,
+		promptVersion: DefaultPromptVersion,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.apiKey == "" {
+		return nil, fmt.Errorf("gemini API key not set")
+	}
+	if _, ok := prompts[o.promptVersion]; !ok {
+		return nil, fmt.Errorf("unknown prompt version %q", o.promptVersion)
+	}
+
+	// Share one pooled HTTP client across all requests made by this service
+	httpClient := o.httpClient
+	if httpClient == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.MaxIdleConns = 100
+		transport.MaxIdleConnsPerHost = 20
+		transport.IdleConnTimeout = 90 * time.Second
+		httpClient = &http.Client{Transport: transport}
 	}
 
 	ctx := context.Background()
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  apiKey,
-		Backend: genai.BackendGeminiAPI,
+		APIKey:     o.apiKey,
+		Backend:    genai.BackendGeminiAPI,
+		HTTPClient: httpClient,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
 	}
 
 	return &Service{
-		client: client,
-		model:  "gemini-2.0-flash-exp", // Using the latest model
+		client:        client,
+		httpClient:    httpClient,
+		model:         o.model,
+		promptVersion: o.promptVersion,
+		autoContinue:  o.autoContinue,
 	}, nil
 }
 
-// Close closes the Gemini client connection
+// Model returns the Gemini model used by the service
+func (s *Service) Model() string {
+	return s.model
+}
+
+// PromptVersion returns the prompt version used by the service
+func (s *Service) PromptVersion() string {
+	return s.promptVersion
+}
+
+// Validate checks that the API key is accepted and the configured model
+// exists by fetching the model metadata
+func (s *Service) Validate(ctx context.Context) error {
+	if _, err := s.client.Models.Get(ctx, s.model, nil); err != nil {
+		return fmt.Errorf("failed to validate Gemini model %q: %w", s.model, err)
+	}
+	return nil
+}
+
+// Close releases the pooled connections held by the service. The genai.Client
+// itself has no Close method, so only the underlying HTTP client is drained.
 func (s *Service) Close() error {
-	// The genai.Client doesn't have a Close method in this version
+	if s.httpClient != nil {
+		s.httpClient.CloseIdleConnections()
+	}
 	return nil
 }
 
 // AnalyzeJob analyzes a job posting and generates a comprehensive response
 func (s *Service) AnalyzeJob(ctx context.Context, req JobAnalysisRequest) (*JobAnalysisResponse, error) {
-	prompt := s.buildAnalysisPrompt(req)
-	log.Printf("Gemini analyze job request: title=%q budget=%q skills=%q", req.JobTitle, req.Budget, req.Skills)
-	log.Printf("Prompt length: %d characters", len(prompt))
-
-	resp, err := s.client.Models.GenerateContent(ctx, s.model, []*genai.Content{
-		{
-			Role: "user",
-			Parts: []*genai.Part{
-				{Text: prompt},
-			},
-		},
-	}, nil)
+	analysis, err := s.AnalyzeJobDetailed(ctx, req)
 	if err != nil {
-		log.Printf("GenerateContent failed: %v", err)
-		return nil, fmt.Errorf("failed to generate content: %w", err)
+		return nil, err
+	}
+	return analysis.Response, nil
+}
+
+// AnalyzeJobDetailed analyzes a job posting and also reports the raw model
+// output, whether it could be parsed and how long the call took
+func (s *Service) AnalyzeJobDetailed(ctx context.Context, req JobAnalysisRequest) (analysis *Analysis, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "gemini.AnalyzeJob", trace.WithAttributes(
+		attribute.String("gen_ai.system", "gemini"),
+		attribute.String("gen_ai.request.model", s.model),
+		attribute.String("upwork_buddy.prompt_version", s.promptVersion),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	_, promptSpan := tracing.Tracer().Start(ctx, "gemini.build_prompt")
+	prompt := prompts[s.promptVersion](req)
+	promptSpan.SetAttributes(attribute.Int("upwork_buddy.prompt_length", len(prompt)))
+	promptSpan.End()
+	slog.DebugContext(ctx, "gemini analyze job",
+		"prompt_length", len(prompt), "prompt_version", s.promptVersion, "model", s.model)
+
+	started := time.Now()
+	rawText, err := s.generate(ctx, prompt)
+	// Out of continuations: keep what was written if it can be repaired
+	var truncErr *ResponseError
+	truncated := errors.As(err, &truncErr) && truncErr.Kind == ErrTruncated && truncErr.Partial != ""
+	if truncated {
+		rawText = truncErr.Partial
+	} else if err != nil {
+		return nil, err
 	}
+	latency := time.Since(started)
 
 	// Parse the response
-	log.Printf("GenerateContent succeeded: %d candidates", len(resp.Candidates))
-	result := s.parseResponse(resp)
-	log.Printf("Parsed analysis result: proposal length=%d spec_sheet length=%d", len(result.Proposal), len(result.SpecSheetPrompt))
-	return result, nil
+	result, parseErr := parseAnalysisText(ctx, rawText)
+	switch {
+	case parseErr != nil && truncated:
+		slog.WarnContext(ctx, "truncated gemini response could not be repaired", "error", parseErr)
+		return nil, err
+	case parseErr != nil:
+		slog.WarnContext(ctx, "gemini response is not valid JSON, returning raw text", "error", parseErr)
+		result = fallbackResponse(rawText)
+	case truncated:
+		slog.InfoContext(ctx, "returning partial analysis of truncated gemini response", "length", len(rawText))
+		result.Partial = true
+	}
+	span.SetAttributes(attribute.Bool("upwork_buddy.partial", result.Partial))
+
+	return &Analysis{
+		Response:      result,
+		RawText:       rawText,
+		ParseError:    parseErr,
+		Model:         s.model,
+		PromptVersion: s.promptVersion,
+		Latency:       latency,
+	}, nil
 }
 
-// buildAnalysisPrompt constructs the comprehensive prompt for the AI
-func (s *Service) buildAnalysisPrompt(req JobAnalysisRequest) string {
-	return fmt.Sprintf(`You are an expert freelance consultant helping contractors on Upwork create winning proposals and project plans.
-
-JOB POSTING:
-Title: %s
-Description: %s
-Budget: %s
-Required Skills: %s
-
-CONTRACTOR PROFILE:
-Profile: %s
-Skills: %s
-
-Please provide a comprehensive analysis with the following sections:
-
-1. PROPOSAL (2-3 paragraphs)
-Write a compelling, professional yet relatable proposal that:
-- Demonstrates understanding of the project requirements
-- Highlights relevant experience and skills
-- Shows enthusiasm and reliability
-- Uses a tone that matches the job posting's formality level
-
-2. SPEC SHEET PROMPT
-Create a detailed prompt that can be used with AI coding agents (GitHub Copilot, Jules, etc.) to generate a technical specification document. This prompt should include:
-- Project requirements breakdown
-- Technical architecture considerations
-- Implementation approach
-- Key deliverables
-- Testing and QA requirements
-
-3. TIME ESTIMATE
-Provide a realistic time estimate broken down by:
-- Total hours required
-- Breakdown by major project phases
-- Buffer time for revisions and feedback
-
-4. WORKLOAD DIVISION
-Suggest how to divide work between:
-- AI agents (GitHub Copilot, Jules): tasks suitable for automation, code generation, repetitive work
-- Human contractor: tasks requiring judgment, creative decisions, client communication, QA, strategic planning
-Include specific percentages and reasoning.
-
-5. QUESTIONS FOR CLIENT (5-7 questions)
-List strategic questions to ask the client to:
-- Clarify requirements
-- Understand their goals and priorities
-- Set proper expectations
-- Establish a smooth workflow
-
-6. TIPS AND ADVICE (4-6 points)
-Provide actionable advice on:
-- Setting clear deliverables and milestones
-- Managing client expectations
-- QA and testing approach
-- Handoff procedures
-- Communication best practices
-
-7. TONE ANALYSIS
-Analyze the job posting's tone (formal, casual, technical, etc.) and suggest the best communication approach.
-
-Format your response as JSON with these exact keys:
-{
-  "proposal": "...",
-  "spec_sheet_prompt": "...",
-  "time_estimate": "...",
-  "workload_division": "...",
-  "questions_for_client": ["...", "..."],
-  "tips_and_advice": ["...", "..."],
-  "tone_analysis": "..."
-}`,
-		req.JobTitle,
-		req.JobDescription,
-		req.Budget,
-		req.Skills,
-		req.UserProfile,
-		req.UserSkills,
-	)
+// GenerateText sends a free-form prompt to the model and returns the text of
+// the reply. It is used for auxiliary calls such as LLM-judge scoring.
+func (s *Service) GenerateText(ctx context.Context, prompt string) (string, error) {
+	return s.generate(ctx, prompt)
 }
 
-// parseResponse parses the AI response into structured data
-func (s *Service) parseResponse(resp *genai.GenerateContentResponse) *JobAnalysisResponse {
-	// Extract text from response
-	var fullText string
-	var candidateCount int
-	for _, candidate := range resp.Candidates {
-		candidateCount++
-		if candidate.Content != nil {
-			for _, part := range candidate.Content.Parts {
-				if part.Text != "" {
-					fullText += part.Text
-				}
-			}
+// continuePrompt is sent after a MAX_TOKENS finish to resume the answer
+const continuePrompt = "Continue exactly where you stopped. Do not repeat any text you already wrote and do not add commentary."
+
+// generate sends the prompt and returns the text of the first candidate.
+// Truncated answers are resumed up to s.autoContinue times; unusable responses
+// are reported as a *ResponseError. Token usage of all calls is added to the
+// span in ctx.
+func (s *Service) generate(ctx context.Context, prompt string) (string, error) {
+	contents := []*genai.Content{genai.NewContentFromText(prompt, genai.RoleUser)}
+
+	var fullText strings.Builder
+	var inputTokens, outputTokens int
+	defer func() {
+		trace.SpanFromContext(ctx).SetAttributes(
+			attribute.Int("gen_ai.usage.input_tokens", inputTokens),
+			attribute.Int("gen_ai.usage.output_tokens", outputTokens),
+		)
+	}()
+
+	for attempt := 0; ; attempt++ {
+		resp, err := s.generateOnce(ctx, contents, attempt)
+		if err != nil {
+			slog.ErrorContext(ctx, "gemini generate content failed", "model", s.model, "error", err)
+			return "", fmt.Errorf("failed to generate content: %w", err)
+		}
+		if usage := resp.UsageMetadata; usage != nil {
+			inputTokens += int(usage.PromptTokenCount)
+			outputTokens += int(usage.CandidatesTokenCount)
 		}
+		slog.DebugContext(ctx, "gemini generate content succeeded", "candidates", len(resp.Candidates), "attempt", attempt)
+
+		text, err := candidateText(resp)
+		fullText.WriteString(text)
+		if err == nil {
+			return fullText.String(), nil
+		}
+
+		if errors.Is(err, ErrTruncated) && attempt < s.autoContinue {
+			slog.InfoContext(ctx, "gemini response truncated, continuing",
+				"length", fullText.Len(), "attempt", attempt+1, "max_attempts", s.autoContinue)
+			contents = append(contents,
+				genai.NewContentFromText(text, genai.RoleModel),
+				genai.NewContentFromText(continuePrompt, genai.RoleUser),
+			)
+			continue
+		}
+
+		var respErr *ResponseError
+		if errors.As(err, &respErr) && respErr.Kind == ErrTruncated {
+			respErr.Partial = fullText.String()
+		}
+		slog.WarnContext(ctx, "gemini returned unusable response", "error", err)
+		return "", err
 	}
-	log.Printf("parseResponse: collected text length=%d from %d candidates", len(fullText), candidateCount)
+}
 
-	// Log the raw response for debugging
-	if len(fullText) > 0 {
-		preview := fullText
-		if len(preview) > 500 {
-			preview = preview[:500] + "..."
+// generateOnce makes a single GenerateContent call in its own span and
+// records its metrics. Unusable responses are returned without error; the
+// caller classifies them.
+func (s *Service) generateOnce(ctx context.Context, contents []*genai.Content, attempt int) (resp *genai.GenerateContentResponse, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "gemini.generate_content", trace.WithAttributes(
+		attribute.String("gen_ai.request.model", s.model),
+		attribute.Int("upwork_buddy.attempt", attempt),
+	))
+	started := time.Now()
+
+	resp, err = s.client.Models.GenerateContent(ctx, s.model, contents, nil)
+	var classErr error
+	if err == nil {
+		_, classErr = candidateText(resp)
+		if len(resp.Candidates) > 0 {
+			span.SetAttributes(attribute.String("gen_ai.response.finish_reasons", string(resp.Candidates[0].FinishReason)))
+		}
+		if usage := resp.UsageMetadata; usage != nil {
+			span.SetAttributes(
+				attribute.Int("gen_ai.usage.input_tokens", int(usage.PromptTokenCount)),
+				attribute.Int("gen_ai.usage.output_tokens", int(usage.CandidatesTokenCount)),
+			)
 		}
-		log.Printf("parseResponse: RAW RESPONSE START\n%s\nparseResponse: RAW RESPONSE END", preview)
 	}
+	s.observe(started, resp, errors.Join(err, classErr))
+	tracing.End(span, errors.Join(err, classErr))
+	return resp, err
+}
 
-	// Strip markdown code block if present
-	fullText = strings.TrimSpace(fullText)
-	originalLength := len(fullText)
+// observe records the latency, token usage and error class of one call.
+// Truncated responses that are continued still count as errors so the
+// truncation rate stays visible.
+func (s *Service) observe(started time.Time, resp *genai.GenerateContentResponse, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+		metrics.LLMErrors.WithLabelValues(s.model, ErrorClass(err)).Inc()
+	}
+	metrics.LLMDuration.WithLabelValues(s.model, outcome).Observe(time.Since(started).Seconds())
 
-	// Remove ```json and ``` wrappers
-	jsonBlockRegex := regexp.MustCompile("(?s)^```(?:json)?\\s*\\n?(.*?)\\n?```$")
-	if matches := jsonBlockRegex.FindStringSubmatch(fullText); len(matches) > 1 {
-		fullText = strings.TrimSpace(matches[1])
-		log.Printf("parseResponse: stripped markdown wrapper (before=%d, after=%d)", originalLength, len(fullText))
-		preview := fullText
-		if len(preview) > 300 {
-			preview = preview[:300] + "..."
-		}
-		log.Printf("parseResponse: STRIPPED JSON START\n%s\nparseResponse: STRIPPED JSON END", preview)
+	if resp == nil || resp.UsageMetadata == nil {
+		return
 	}
+	usage := resp.UsageMetadata
+	metrics.LLMTokens.WithLabelValues(s.model, "prompt").Add(float64(usage.PromptTokenCount))
+	metrics.LLMTokens.WithLabelValues(s.model, "output").Add(float64(usage.CandidatesTokenCount))
+	if usage.ThoughtsTokenCount > 0 {
+		metrics.LLMTokens.WithLabelValues(s.model, "thoughts").Add(float64(usage.ThoughtsTokenCount))
+	}
+}
+
+// parseAnalysisText decodes the model output into a JobAnalysisResponse,
+// stripping markdown fences and unwrapping double-encoded JSON. Only sizes
+// and error positions are logged: the output echoes the user's profile.
+func parseAnalysisText(ctx context.Context, fullText string) (*JobAnalysisResponse, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "gemini.parse_response")
+	defer span.End()
+	parsed := func(result string) {
+		metrics.ParseResults.WithLabelValues(result).Inc()
+		span.SetAttributes(attribute.String("upwork_buddy.parse_result", result))
+	}
+
+	originalLength := len(fullText)
+	fullText = stripCodeFence(fullText)
+	slog.DebugContext(ctx, "parsing analysis", "raw_length", originalLength, "stripped_length", len(fullText))
 
 	// Try to parse as JSON directly
 	var result JobAnalysisResponse
-	if err := json.Unmarshal([]byte(fullText), &result); err == nil {
-		log.Printf("parseResponse: ✅ json.Unmarshal succeeded")
-		log.Printf("parseResponse: proposal length=%d, spec_sheet length=%d, questions=%d, tips=%d",
-			len(result.Proposal), len(result.SpecSheetPrompt),
-			len(result.QuestionsForClient), len(result.TipsAndAdvice))
-
+	err := json.Unmarshal([]byte(fullText), &result)
+	if err == nil {
 		// If the proposal field contains JSON-like content, it might be double-encoded
-		// Check if proposal starts with { and try to re-parse
 		if strings.HasPrefix(strings.TrimSpace(result.Proposal), "{") {
-			log.Printf("parseResponse: proposal looks like nested JSON, attempting to re-parse...")
 			var innerResult JobAnalysisResponse
 			if err2 := json.Unmarshal([]byte(result.Proposal), &innerResult); err2 == nil {
-				log.Printf("parseResponse: ✅ successfully parsed nested JSON from proposal field")
-				return &innerResult
-			}
-			log.Printf("parseResponse: ⚠️ nested JSON parse failed, using outer result")
-		}
-		return &result
-	} else {
-		log.Printf("parseResponse: ❌ json.Unmarshal FAILED: %v", err)
-		log.Printf("parseResponse: JSON error details - Type: %T, Message: %s", err, err.Error())
-
-		// Try to identify the specific JSON error location
-		if syntaxErr, ok := err.(*json.SyntaxError); ok {
-			log.Printf("parseResponse: JSON syntax error at byte offset %d", syntaxErr.Offset)
-			// Show context around the error
-			start := int(syntaxErr.Offset) - 100
-			if start < 0 {
-				start = 0
-			}
-			end := int(syntaxErr.Offset) + 100
-			if end > len(fullText) {
-				end = len(fullText)
+				slog.DebugContext(ctx, "parsed nested JSON from proposal field")
+				parsed("ok")
+				return &innerResult, nil
 			}
-			log.Printf("parseResponse: ERROR CONTEXT: ...%s...", fullText[start:end])
 		}
+		slog.DebugContext(ctx, "parsed analysis",
+			"proposal_length", len(result.Proposal),
+			"spec_sheet_length", len(result.SpecSheetPrompt),
+			"questions", len(result.QuestionsForClient),
+			"tips", len(result.TipsAndAdvice))
+		parsed("ok")
+		return &result, nil
+	}
 
-		preview := fullText
-		if len(preview) > 500 {
-			preview = preview[:500] + "..."
-		}
-		log.Printf("parseResponse: FAILED JSON CONTENT START\n%s\nparseResponse: FAILED JSON CONTENT END", preview)
-
-		return &JobAnalysisResponse{
-			Proposal:           fullText,
-			SpecSheetPrompt:    "Failed to parse response. Please check the API server logs.",
-			TimeEstimate:       json.RawMessage(`""`),
-			WorkloadDivision:   json.RawMessage(`""`),
-			QuestionsForClient: []string{},
-			TipsAndAdvice:      []string{},
-			ToneAnalysis:       "",
-		}
+	attrs := []any{"error", err, "length", len(fullText)}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		attrs = append(attrs, "offset", syntaxErr.Offset)
+	}
+	slog.InfoContext(ctx, "analysis JSON is malformed, attempting repair", attrs...)
+
+	// Fix common LLM JSON defects and try again
+	repaired, truncated := repairJSON(fullText)
+	var repairedResult JobAnalysisResponse
+	if err2 := json.Unmarshal([]byte(repaired), &repairedResult); err2 == nil {
+		slog.InfoContext(ctx, "parsed analysis after JSON repair", "truncated", truncated)
+		repairedResult.Partial = truncated
+		parsed("repaired")
+		return &repairedResult, nil
+	}
+
+	// Salvage whatever sections can be decoded on their own
+	if partial, ok := extractFields(fullText); ok {
+		slog.WarnContext(ctx, "recovered partial analysis field by field")
+		partial.Partial = true
+		parsed("partial")
+		return partial, nil
+	}
+
+	parsed("failed")
+	return nil, fmt.Errorf("failed to decode analysis JSON: %w", err)
+}
+
+// stripCodeFence removes a surrounding ```json ... ``` markdown block
+func stripCodeFence(fullText string) string {
+	fullText = strings.TrimSpace(fullText)
+	if matches := jsonBlockRegex.FindStringSubmatch(fullText); len(matches) > 1 {
+		return strings.TrimSpace(matches[1])
+	}
+	return fullText
+}
+
+var jsonBlockRegex = regexp.MustCompile("(?s)^```(?:json)?\\s*\\n?(.*?)\\n?```$")
+
+// fallbackResponse wraps unparseable model output so the caller still sees it
+func fallbackResponse(fullText string) *JobAnalysisResponse {
+	return &JobAnalysisResponse{
+		Proposal:           stripCodeFence(fullText),
+		SpecSheetPrompt:    parseFailureMessage,
+		TimeEstimate:       json.RawMessage(`""`),
+		WorkloadDivision:   json.RawMessage(`""`),
+		QuestionsForClient: []string{},
+		TipsAndAdvice:      []string{},
+		ToneAnalysis:       "",
 	}
 }
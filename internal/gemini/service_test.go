@@ -0,0 +1,221 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+// newTestService returns a Service whose HTTP traffic is served from the named
+// fixture in testdata. Run with GEMINI_RECORD=1 and a real GEMINI_API_KEY to
+// refresh the fixture from the live API.
+func newTestService(t *testing.T, fixture string, opts ...Option) *Service {
+	t.Helper()
+
+	mode := RecorderModeFromEnv()
+	recorder, err := NewRecorder(filepath.Join("testdata", fixture), mode)
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := recorder.Save(); err != nil {
+			t.Errorf("failed to save fixture: %v", err)
+		}
+	})
+
+	apiKey := "test-key"
+	if mode == ModeRecord {
+		apiKey = os.Getenv("GEMINI_API_KEY")
+	}
+	opts = append([]Option{WithAPIKey(apiKey), WithHTTPClient(recorder.Client())}, opts...)
+	service, err := New(opts...)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	return service
+}
+
+func TestAnalyzeJobReplay(t *testing.T) {
+	service := newTestService(t, "analyze_job.json")
+
+	result, err := service.AnalyzeJob(context.Background(), JobAnalysisRequest{
+		JobTitle:       "Go developer for REST API with PostgreSQL",
+		JobDescription: "Build shipment tracking endpoints backed by PostgreSQL and deployed with Docker.",
+		Skills:         "Go, PostgreSQL, Docker",
+		UserProfile:    "Backend engineer with 8 years of Go experience.",
+		UserSkills:     "Go, PostgreSQL, Docker",
+	})
+	if err != nil {
+		t.Fatalf("AnalyzeJob returned error: %v", err)
+	}
+	if result.Proposal == "" || result.SpecSheetPrompt == parseFailureMessage {
+		t.Fatalf("expected a parsed analysis, got %+v", result)
+	}
+	if len(result.QuestionsForClient) != 5 {
+		t.Errorf("expected 5 questions, got %d", len(result.QuestionsForClient))
+	}
+	if len(result.TimeEstimate) == 0 || result.TimeEstimate[0] != '{' {
+		t.Errorf("expected structured time estimate, got %s", result.TimeEstimate)
+	}
+}
+
+func TestParseAnalysisText(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		wantProposal string
+		wantFailure  bool
+	}{
+		{
+			name:         "plain json",
+			text:         `{"proposal": "Hello", "questions_for_client": ["Why?"]}`,
+			wantProposal: "Hello",
+		},
+		{
+			name:         "markdown fenced",
+			text:         "```json\n{\"proposal\": \"Fenced\"}\n```",
+			wantProposal: "Fenced",
+		},
+		{
+			name:         "double encoded proposal",
+			text:         `{"proposal": "{\"proposal\": \"Inner\"}"}`,
+			wantProposal: "Inner",
+		},
+		{
+			name:         "not json",
+			text:         "Sorry, I cannot help with that.",
+			wantProposal: "Sorry, I cannot help with that.",
+			wantFailure:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseAnalysisText(context.Background(), tt.text)
+			if (err != nil) != tt.wantFailure {
+				t.Fatalf("parseAnalysisText returned error %v, want failure %v", err, tt.wantFailure)
+			}
+			if err != nil {
+				result = fallbackResponse(tt.text)
+			}
+			if result.Proposal != tt.wantProposal {
+				t.Errorf("proposal = %q, want %q", result.Proposal, tt.wantProposal)
+			}
+			if failed := result.SpecSheetPrompt == parseFailureMessage; failed != tt.wantFailure {
+				t.Errorf("parse failure = %v, want %v", failed, tt.wantFailure)
+			}
+		})
+	}
+}
+
+func TestCandidateTextFinishReasons(t *testing.T) {
+	tests := []struct {
+		name string
+		resp *genai.GenerateContentResponse
+		want error
+	}{
+		{
+			name: "prompt blocked",
+			resp: &genai.GenerateContentResponse{PromptFeedback: &genai.GenerateContentResponsePromptFeedback{
+				BlockReason: genai.BlockedReasonSafety,
+			}},
+			want: ErrBlocked,
+		},
+		{
+			name: "candidate blocked",
+			resp: candidateResponse(`{"proposal": "Hi"}`, genai.FinishReasonSafety),
+			want: ErrBlocked,
+		},
+		{
+			name: "truncated",
+			resp: candidateResponse(`{"proposal": "Hi, I am`, genai.FinishReasonMaxTokens),
+			want: ErrTruncated,
+		},
+		{
+			name: "recitation",
+			resp: candidateResponse(`{"proposal": "Hi"}`, genai.FinishReasonRecitation),
+			want: ErrRecitation,
+		},
+		{
+			name: "no candidates",
+			resp: &genai.GenerateContentResponse{},
+			want: ErrEmpty,
+		},
+		{
+			name: "blank text",
+			resp: candidateResponse("  ", genai.FinishReasonStop),
+			want: ErrEmpty,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := candidateText(tt.resp)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+			var respErr *ResponseError
+			if !errors.As(err, &respErr) {
+				t.Fatalf("expected *ResponseError, got %T", err)
+			}
+		})
+	}
+}
+
+func TestAnalyzeJobAutoContinue(t *testing.T) {
+	req := JobAnalysisRequest{JobTitle: "Landing page", JobDescription: "Build a landing page."}
+
+	service := newTestService(t, "analyze_job_truncated.json", WithAutoContinue(1))
+	result, err := service.AnalyzeJob(context.Background(), req)
+	if err != nil {
+		t.Fatalf("AnalyzeJob returned error: %v", err)
+	}
+	if result.Proposal != "Hello from a continued answer" || result.ToneAnalysis != "Friendly" {
+		t.Errorf("expected joined analysis, got %+v", result)
+	}
+}
+
+func TestAnalyzeJobTruncated(t *testing.T) {
+	req := JobAnalysisRequest{JobTitle: "Landing page", JobDescription: "Build a landing page."}
+
+	// Without auto-continue the cut off first answer is repaired
+	service := newTestService(t, "analyze_job_truncated.json")
+	result, err := service.AnalyzeJob(context.Background(), req)
+	if err != nil {
+		t.Fatalf("AnalyzeJob returned error: %v", err)
+	}
+	if !result.Partial || result.Proposal != "Hello from a cont" {
+		t.Errorf("expected a partial analysis of the truncated answer, got %+v", result)
+	}
+}
+
+func candidateResponse(text string, reason genai.FinishReason) *genai.GenerateContentResponse {
+	return &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+		Content:      genai.NewContentFromText(text, genai.RoleModel),
+		FinishReason: reason,
+	}}}
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&ResponseError{Kind: ErrBlocked}, "blocked"},
+		{fmt.Errorf("failed to generate content: %w", context.DeadlineExceeded), "timeout"},
+		{fmt.Errorf("failed to generate content: %w", genai.APIError{Code: 429}), "rate_limited"},
+		{genai.APIError{Code: 503}, "server"},
+		{genai.APIError{Code: 400}, "client"},
+		{errors.New("connection reset"), "network"},
+	}
+	for _, tt := range tests {
+		if got := ErrorClass(tt.err); got != tt.want {
+			t.Errorf("ErrorClass(%v) = %q; want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Package lifecycle coordinates graceful shutdown. Shutdown runs in stages:
+// stop accepting work, drain what is running within a deadline, then close
+// clients and pools in the order they were registered.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stage is a step of the shutdown sequence. Stages run in the order below;
+// hooks of one stage run in registration order.
+type Stage int
+
+const (
+	// StageStop hooks stop taking new work, e.g. close listeners and queues
+	StageStop Stage = iota
+	// StageDrain hooks wait for running work. Their context ends a grace
+	// period after the drain deadline, when Context is cancelled.
+	StageDrain
+	// StageClose hooks release clients, pools and exporters
+	StageClose
+)
+
+var stageNames = [...]string{"stop", "drain", "close"}
+
+func (s Stage) String() string {
+	return stageNames[s]
+}
+
+// finalTimeout bounds the stop and close stages, and is the grace
+// period cancelled work gets to return after the drain deadline
+const finalTimeout = 10 * time.Second
+
+type hook struct {
+	stage Stage
+	name  string
+	fn    func(context.Context) error
+}
+
+// Manager tracks in-flight work and runs the shutdown hooks. A nil Manager
+// accepts all work and has nothing to shut down, which keeps tests simple.
+type Manager struct {
+	drainTimeout time.Duration
+
+	// ctx is handed to work that should be cut off when draining takes too
+	// long; it is cancelled at the drain deadline
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	stopping atomic.Bool
+	inflight sync.WaitGroup
+	active   atomic.Int64
+
+	mu    sync.Mutex
+	hooks []hook
+}
+
+// New returns a manager that waits up to drainTimeout for running work
+func New(drainTimeout time.Duration) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{drainTimeout: drainTimeout, ctx: ctx, cancel: cancel}
+}
+
+// Context is cancelled when the drain deadline passes. Long-running work
+// should derive its context from it so it stops instead of being killed.
+func (m *Manager) Context() context.Context {
+	if m == nil {
+		return context.Background()
+	}
+	return m.ctx
+}
+
+// Stopping reports whether shutdown has begun
+func (m *Manager) Stopping() bool {
+	return m != nil && m.stopping.Load()
+}
+
+// Track registers a unit of work, such as an analysis request, that the
+// drain stage waits for. It returns false once shutdown has begun; the
+// caller must then refuse the work. done must be called when it finishes.
+func (m *Manager) Track() (done func(), ok bool) {
+	if m == nil {
+		return func() {}, true
+	}
+	// Add before checking so Shutdown cannot miss work that passed the check
+	m.inflight.Add(1)
+	m.active.Add(1)
+	release := sync.OnceFunc(func() {
+		m.active.Add(-1)
+		m.inflight.Done()
+	})
+	if m.stopping.Load() {
+		release()
+		return nil, false
+	}
+	return release, true
+}
+
+// OnShutdown registers fn to run during stage
+func (m *Manager) OnShutdown(stage Stage, name string, fn func(context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook{stage: stage, name: name, fn: fn})
+}
+
+// Shutdown runs every stage once. Failing hooks do not stop the sequence;
+// their errors are returned together.
+func (m *Manager) Shutdown() error {
+	if !m.stopping.CompareAndSwap(false, true) {
+		return errors.New("shutdown already in progress")
+	}
+	started := time.Now()
+	slog.Info("shutdown started", "drain_timeout", m.drainTimeout.String(), "in_flight", m.active.Load())
+
+	var errs []error
+	errs = append(errs, m.runStage(StageStop, finalTimeout)...)
+
+	// Work gets the drain timeout to finish. At the deadline its context is
+	// cancelled, and drain hooks get finalTimeout more to see it return.
+	deadline := time.AfterFunc(m.drainTimeout, func() {
+		slog.Warn("drain deadline passed, cancelling remaining work", "in_flight", m.active.Load())
+		m.cancel()
+	})
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), m.drainTimeout+finalTimeout)
+	drained := make(chan struct{})
+	go func() {
+		m.inflight.Wait()
+		close(drained)
+	}()
+	errs = append(errs, m.runHooks(drainCtx, StageDrain)...)
+	select {
+	case <-drained:
+	case <-drainCtx.Done():
+		errs = append(errs, fmt.Errorf("%d tasks still running after cancellation", m.active.Load()))
+	}
+	deadline.Stop()
+	cancelDrain()
+	m.cancel()
+
+	errs = append(errs, m.runStage(StageClose, finalTimeout)...)
+
+	slog.Info("shutdown finished", "duration_ms", time.Since(started).Milliseconds(), "errors", len(errs))
+	return errors.Join(errs...)
+}
+
+func (m *Manager) runStage(stage Stage, timeout time.Duration) []error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return m.runHooks(ctx, stage)
+}
+
+func (m *Manager) runHooks(ctx context.Context, stage Stage) []error {
+	m.mu.Lock()
+	hooks := append([]hook(nil), m.hooks...)
+	m.mu.Unlock()
+
+	var errs []error
+	for _, h := range hooks {
+		if h.stage != stage {
+			continue
+		}
+		started := time.Now()
+		err := h.fn(ctx)
+		slog.Info("shutdown hook finished", "stage", stage.String(), "hook", h.name,
+			"duration_ms", time.Since(started).Milliseconds(), "error", err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", stage, h.name, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,110 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShutdownOrder(t *testing.T) {
+	m := New(time.Second)
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string, err error) func(context.Context) error {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return err
+		}
+	}
+	// Registered out of order; stages decide when hooks run
+	m.OnShutdown(StageClose, "llm", record("llm", nil))
+	m.OnShutdown(StageClose, "db", record("db", errors.New("pool busy")))
+	m.OnShutdown(StageDrain, "http", record("http", nil))
+	m.OnShutdown(StageStop, "queue", record("queue", nil))
+
+	done, ok := m.Track()
+	if !ok {
+		t.Fatal("expected work to be accepted before shutdown")
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		record("work", nil)(nil)
+		done()
+	}()
+
+	err := m.Shutdown()
+	if err == nil || !strings.Contains(err.Error(), "close db: pool busy") {
+		t.Errorf("expected the db error; got %v", err)
+	}
+	want := "queue http work llm db"
+	if got := strings.Join(order, " "); got != want {
+		t.Errorf("expected order %q; got %q", want, got)
+	}
+}
+
+func TestTrackRefusedWhileStopping(t *testing.T) {
+	m := New(time.Second)
+	started := make(chan bool, 1)
+	m.OnShutdown(StageStop, "probe", func(ctx context.Context) error {
+		_, ok := m.Track()
+		started <- ok
+		return nil
+	})
+
+	if err := m.Shutdown(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if <-started {
+		t.Error("expected work to be refused once shutdown began")
+	}
+	if !m.Stopping() {
+		t.Error("expected Stopping to report true")
+	}
+	if err := m.Shutdown(); err == nil {
+		t.Error("expected a second shutdown to fail")
+	}
+}
+
+func TestDrainTimeoutCancelsWork(t *testing.T) {
+	m := New(50 * time.Millisecond)
+
+	var stopped time.Duration
+	started := time.Now()
+	done, ok := m.Track()
+	if !ok {
+		t.Fatal("expected work to be accepted before shutdown")
+	}
+	go func() {
+		defer done()
+		<-m.Context().Done()
+		stopped = time.Since(started)
+	}()
+
+	if err := m.Shutdown(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stopped < 50*time.Millisecond {
+		t.Errorf("expected the worker to run until the drain deadline; stopped after %s", stopped)
+	}
+	if m.Context().Err() == nil {
+		t.Error("expected the work context to be cancelled")
+	}
+}
+
+func TestNilManager(t *testing.T) {
+	var m *Manager
+	done, ok := m.Track()
+	if !ok {
+		t.Fatal("expected a nil manager to accept work")
+	}
+	done()
+	if m.Stopping() || m.Context().Err() != nil {
+		t.Error("expected a nil manager to never stop")
+	}
+}
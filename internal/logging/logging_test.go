@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRequestIDAndRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Options{Level: "debug"})
+	ctx := WithRequestID(context.Background(), "req-123")
+
+	logger.InfoContext(ctx, "analysis requested",
+		"title", "Go developer",
+		"user_profile", "Jane Doe, 10 years in Go",
+		"error", errors.New("call failed with Authorization: Bearer abcdefghijkl"),
+		"url", "https://example.com/?key=AIzaSyA1234567890abcdefghijklmnopqrstu")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected JSON record; got %q", buf.String())
+	}
+	if record["request_id"] != "req-123" {
+		t.Errorf("expected request_id; got %v", record["request_id"])
+	}
+	if record["title"] != "Go developer" {
+		t.Errorf("expected title to be kept; got %v", record["title"])
+	}
+	for _, secret := range []string{"Jane Doe", "abcdefghijkl", "AIzaSy"} {
+		if strings.Contains(buf.String(), secret) {
+			t.Errorf("expected %q to be redacted; got %s", secret, buf.String())
+		}
+	}
+	if record["user_profile"] != "[REDACTED] len=24" {
+		t.Errorf("expected redacted profile with length; got %v", record["user_profile"])
+	}
+}
+
+func TestLevels(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Options{Level: "warn", Format: "text"})
+
+	logger.Info("hidden")
+	logger.Warn("shown")
+
+	if strings.Contains(buf.String(), "hidden") || !strings.Contains(buf.String(), "msg=shown") {
+		t.Errorf("expected only warn record in text format; got %q", buf.String())
+	}
+	if ParseLevel("nonsense") != slog.LevelInfo {
+		t.Error("expected unknown level to default to info")
+	}
+}
+
+func TestTraceContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Options{})
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	logger.InfoContext(ctx, "traced")
+	logger.Info("untraced")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`) || !strings.Contains(lines[0], `"span_id":"00f067aa0ba902b7"`) {
+		t.Errorf("expected trace and span IDs; got %s", lines[0])
+	}
+	if strings.Contains(lines[1], "trace_id") {
+		t.Errorf("expected no trace_id without a span; got %s", lines[1])
+	}
+}
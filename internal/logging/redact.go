@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// sensitiveKeys are attribute keys whose values are never logged. Profiles
+// and job descriptions hold personal data; the rest are credentials.
+var sensitiveKeys = map[string]bool{
+	"api_key":         true,
+	"authorization":   true,
+	"x-api-key":       true,
+	"password":        true,
+	"token":           true,
+	"user_profile":    true,
+	"profile":         true,
+	"description":     true,
+	"job_description": true,
+	"raw_text":        true,
+}
+
+// secretPattern matches credentials that may end up inside other values,
+// such as error messages that echo a URL
+var secretPattern = regexp.MustCompile(`ubk_[A-Za-z0-9_-]{8,}|AIza[0-9A-Za-z_-]{30,}|(?i)(bearer\s+)[A-Za-z0-9._~+/=-]{8,}`)
+
+// Redacted replaces the value of sensitive attributes
+const Redacted = "[REDACTED]"
+
+// redactAttr is the slog ReplaceAttr hook. Sensitive keys are replaced by
+// their length and credentials are masked wherever they appear in a string.
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		if s, ok := a.Value.Resolve().Any().(string); ok {
+			return slog.String(a.Key, fmt.Sprintf("%s len=%d", Redacted, len(s)))
+		}
+		return slog.String(a.Key, Redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		if s := a.Value.String(); secretPattern.MatchString(s) {
+			return slog.String(a.Key, RedactSecrets(s))
+		}
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, RedactSecrets(err.Error()))
+		}
+	}
+	return a
+}
+
+// RedactSecrets masks API keys and bearer tokens in s
+func RedactSecrets(s string) string {
+	return secretPattern.ReplaceAllStringFunc(s, func(match string) string {
+		if m := secretPattern.FindStringSubmatch(match); m[1] != "" {
+			return m[1] + Redacted
+		}
+		return Redacted
+	})
+}
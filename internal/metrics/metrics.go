@@ -0,0 +1,101 @@
+// Package metrics defines the Prometheus collectors exported on /metrics.
+// Collectors live in a dedicated registry so tests and tools that import the
+// instrumented packages do not share global state with the server.
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "upwork_buddy"
+
+// Registry holds every collector of the process
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	// HTTPRequests counts handled requests by route pattern, method and status
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	// HTTPDuration observes request latency by route pattern and method
+	HTTPDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by route and method.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"route", "method"})
+
+	// LLMDuration observes the latency of single LLM calls
+	LLMDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "llm_request_duration_seconds",
+		Help:      "Latency of LLM generate calls, by model and outcome.",
+		Buckets:   []float64{0.5, 1, 2, 4, 8, 15, 30, 60, 120},
+	}, []string{"model", "outcome"})
+
+	// LLMTokens counts tokens reported by the provider
+	LLMTokens = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_tokens_total",
+		Help:      "Tokens used by LLM calls, by model and type (prompt, output, thoughts).",
+	}, []string{"model", "type"})
+
+	// LLMErrors counts failed LLM calls by error class
+	LLMErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_errors_total",
+		Help:      "Failed LLM calls, by model and error class.",
+	}, []string{"model", "class"})
+
+	// ParseResults counts how model output was decoded
+	ParseResults = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_parse_results_total",
+		Help:      "Decoding of LLM analysis output, by result (ok, repaired, partial, failed).",
+	}, []string{"result"})
+
+	// CacheLookups counts cache hits and misses; the hit ratio is
+	// rate(hit) / rate(hit + miss). The client cache is reported by the
+	// bookmarklet with each analysis request.
+	CacheLookups = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Cache lookups, by cache and result (hit, miss).",
+	}, []string{"cache", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// RegisterDB exports the connection pool statistics of db, the same
+// sql.DBStats reported by the health check. Registering the same database
+// twice is not an error.
+func RegisterDB(db *sql.DB, name string) error {
+	err := Registry.Register(collectors.NewDBStatsCollector(db, name))
+	var already prometheus.AlreadyRegisteredError
+	if errors.As(err, &already) {
+		return nil
+	}
+	return err
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
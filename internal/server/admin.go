@@ -0,0 +1,179 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	dbservice "upwork-buddy/internal/database/service"
+
+	"gorm.io/gorm"
+)
+
+type createUserRequest struct {
+	Email   string `json:"email"`
+	Name    string `json:"name"`
+	IsAdmin bool   `json:"is_admin"`
+}
+
+type userResponse struct {
+	ID              uint       `json:"id"`
+	Email           string     `json:"email"`
+	Name            string     `json:"name"`
+	IsAdmin         bool       `json:"is_admin"`
+	APIKeyPrefix    string     `json:"api_key_prefix,omitempty"`
+	APIKeyCreatedAt *time.Time `json:"api_key_created_at,omitempty"`
+	APIKeyRevokedAt *time.Time `json:"api_key_revoked_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// apiKeyResponse is returned when a key is issued. The key is shown once and
+// cannot be retrieved again.
+type apiKeyResponse struct {
+	User   userResponse `json:"user"`
+	APIKey string       `json:"api_key"`
+}
+
+// createUser handles POST /api/v1/admin/users. The new user is issued an API
+// key straight away.
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request) {
+	var payload createUserRequest
+	if !decodeJSON(w, r, &payload) {
+		return
+	}
+	if violations := validateCreateUserRequest(payload); len(violations) > 0 {
+		writeError(w, r, codeValidationFailed, "The user has invalid fields", violations)
+		return
+	}
+
+	user := dbservice.User{
+		Email:   strings.TrimSpace(payload.Email),
+		Name:    strings.TrimSpace(payload.Name),
+		IsAdmin: payload.IsAdmin,
+	}
+	key, err := assignAPIKey(&user)
+	if err != nil {
+		writeError(w, r, codeInternal, "Failed to generate API key", nil)
+		return
+	}
+	if err := s.db.GetGorm().WithContext(r.Context()).Create(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			writeError(w, r, codeConflict, "A user with this email already exists", nil)
+			return
+		}
+		writeError(w, r, codeDatabaseError, "Failed to create user", nil)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "user created", "user_id", user.ID, "key_prefix", user.APIKeyPrefix)
+	respondWithStatus(w, http.StatusCreated, apiKeyResponse{User: userResponseFromModel(&user), APIKey: key})
+}
+
+// listUsers handles GET /api/v1/admin/users
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
+	var users []dbservice.User
+	if err := s.db.GetGorm().WithContext(r.Context()).Order("id asc").Find(&users).Error; err != nil {
+		writeError(w, r, codeDatabaseError, "Failed to load users", nil)
+		return
+	}
+
+	resp := make([]userResponse, 0, len(users))
+	for i := range users {
+		resp = append(resp, userResponseFromModel(&users[i]))
+	}
+	respondWithJSON(w, resp)
+}
+
+// issueAPIKey handles POST /api/v1/admin/users/{id}/api-key. Any previous key
+// of the user stops working immediately.
+func (s *Server) issueAPIKey(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.loadUser(w, r)
+	if !ok {
+		return
+	}
+
+	key, err := assignAPIKey(user)
+	if err != nil {
+		writeError(w, r, codeInternal, "Failed to generate API key", nil)
+		return
+	}
+	if err := s.db.GetGorm().WithContext(r.Context()).Save(user).Error; err != nil {
+		writeError(w, r, codeDatabaseError, "Failed to store API key", nil)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "api key issued", "user_id", user.ID, "key_prefix", user.APIKeyPrefix)
+	respondWithStatus(w, http.StatusCreated, apiKeyResponse{User: userResponseFromModel(user), APIKey: key})
+}
+
+// revokeAPIKey handles DELETE /api/v1/admin/users/{id}/api-key
+func (s *Server) revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.loadUser(w, r)
+	if !ok {
+		return
+	}
+
+	if user.APIKeyHash != nil && user.APIKeyRevokedAt == nil {
+		now := time.Now()
+		user.APIKeyRevokedAt = &now
+		if err := s.db.GetGorm().WithContext(r.Context()).Save(user).Error; err != nil {
+			writeError(w, r, codeDatabaseError, "Failed to revoke API key", nil)
+			return
+		}
+		slog.InfoContext(r.Context(), "api key revoked", "user_id", user.ID, "key_prefix", user.APIKeyPrefix)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadUser fetches the user named by the {id} path parameter, writing the
+// error response when it cannot
+func (s *Server) loadUser(w http.ResponseWriter, r *http.Request) (*dbservice.User, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, r, codeNotFound, "User not found", nil)
+		return nil, false
+	}
+
+	var user dbservice.User
+	if err := s.db.GetGorm().WithContext(r.Context()).First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeError(w, r, codeNotFound, "User not found", nil)
+			return nil, false
+		}
+		writeError(w, r, codeDatabaseError, "Failed to load user", nil)
+		return nil, false
+	}
+	return &user, true
+}
+
+// assignAPIKey generates a key for user, replacing any previous one, and
+// returns it in clear text
+func assignAPIKey(user *dbservice.User) (string, error) {
+	key, prefix, hash, err := generateAPIKey()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	user.APIKeyHash = &hash
+	user.APIKeyPrefix = prefix
+	user.APIKeyCreatedAt = &now
+	user.APIKeyRevokedAt = nil
+	return key, nil
+}
+
+func userResponseFromModel(user *dbservice.User) userResponse {
+	return userResponse{
+		ID:              user.ID,
+		Email:           user.Email,
+		Name:            user.Name,
+		IsAdmin:         user.IsAdmin,
+		APIKeyPrefix:    user.APIKeyPrefix,
+		APIKeyCreatedAt: user.APIKeyCreatedAt,
+		APIKeyRevokedAt: user.APIKeyRevokedAt,
+		CreatedAt:       user.CreatedAt,
+	}
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	dbservice "upwork-buddy/internal/database/service"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// apiKeyPrefix marks Upwork Buddy keys so they are easy to spot in logs
+	// and secret scanners
+	apiKeyPrefix = "ubk_"
+
+	// apiKeyDisplayLength is how much of a key is kept in clear text so
+	// admins can tell keys apart
+	apiKeyDisplayLength = 12
+)
+
+// errInvalidAPIKey is returned when a key is missing, unknown or revoked
+var errInvalidAPIKey = errors.New("invalid API key")
+
+// generateAPIKey returns a new random key, its display prefix and the hash
+// that is stored in the users table. The key itself is never stored.
+func generateAPIKey() (key, prefix, hash string, err error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", "", "", err
+	}
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b[:])
+	return key, key[:apiKeyDisplayLength], hashAPIKey(key), nil
+}
+
+// hashAPIKey returns the hex SHA-256 of key. Keys carry 256 bits of entropy,
+// so a fast unsalted hash is enough and keeps lookups indexable.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiKeyFromRequest reads the key from an "Authorization: Bearer" header or,
+// failing that, from X-API-Key
+func apiKeyFromRequest(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		scheme, token, ok := strings.Cut(auth, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+// authenticate resolves the caller of r. The bootstrap ADMIN_API_KEY maps to
+// a synthetic admin with ID 0 that exists only to issue the first keys.
+func (s *Server) authenticate(r *http.Request) (*dbservice.User, error) {
+	key := apiKeyFromRequest(r)
+	if key == "" {
+		return nil, errInvalidAPIKey
+	}
+	if s.adminKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(s.adminKey)) == 1 {
+		return &dbservice.User{Name: "bootstrap admin", IsAdmin: true}, nil
+	}
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, errInvalidAPIKey
+	}
+
+	var user dbservice.User
+	err := s.db.GetGorm().WithContext(r.Context()).
+		Where("api_key_hash = ? AND api_key_revoked_at IS NULL", hashAPIKey(key)).
+		First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// requireUser lets through requests made with a user's API key and stores
+// the user in the request context
+func (s *Server) requireUser(next http.HandlerFunc) http.HandlerFunc {
+	return s.withAuthentication(func(w http.ResponseWriter, r *http.Request, user *dbservice.User) bool {
+		if user.ID == 0 {
+			writeError(w, r, codeForbidden, "The admin key can only be used to manage users", nil)
+			return false
+		}
+		return true
+	}, next)
+}
+
+// requireAdmin lets through requests made with the bootstrap admin key or
+// the API key of an admin user
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return s.withAuthentication(func(w http.ResponseWriter, r *http.Request, user *dbservice.User) bool {
+		if !user.IsAdmin {
+			writeError(w, r, codeForbidden, "This endpoint requires an admin API key", nil)
+			return false
+		}
+		return true
+	}, next)
+}
+
+// withAuthentication authenticates the request, applies allow and passes the
+// request on with the user in its context
+func (s *Server) withAuthentication(allow func(http.ResponseWriter, *http.Request, *dbservice.User) bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := s.authenticate(r)
+		if errors.Is(err, errInvalidAPIKey) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="upwork-buddy"`)
+			writeError(w, r, codeUnauthorized, "A valid API key is required", nil)
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to authenticate request", "error", err)
+			writeError(w, r, codeDatabaseError, "Failed to check API key", nil)
+			return
+		}
+		if !allow(w, r, user) {
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), userKey, user)))
+	}
+}
+
+// userFromContext returns the user authenticated by requireUser or
+// requireAdmin, or nil on public routes
+func userFromContext(ctx context.Context) *dbservice.User {
+	user, _ := ctx.Value(userKey).(*dbservice.User)
+	return user
+}
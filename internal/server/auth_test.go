@@ -0,0 +1,94 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testAdminKey = "test-admin-key-0123456789abcdefghij"
+
+func TestAPIKeyFromRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   string
+	}{
+		{name: "none", want: ""},
+		{name: "bearer", header: map[string]string{"Authorization": "Bearer ubk_abc"}, want: "ubk_abc"},
+		{name: "lowercase scheme", header: map[string]string{"Authorization": "bearer ubk_abc"}, want: "ubk_abc"},
+		{name: "other scheme", header: map[string]string{"Authorization": "Basic dXNlcjpwYXNz"}, want: ""},
+		{name: "x-api-key", header: map[string]string{"X-API-Key": " ubk_abc "}, want: "ubk_abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/profile", nil)
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+			if got := apiKeyFromRequest(req); got != tt.want {
+				t.Errorf("expected %q; got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, hash, err := generateAPIKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if !strings.HasPrefix(key, apiKeyPrefix) || !strings.HasPrefix(key, prefix) {
+		t.Errorf("unexpected key %q with prefix %q", key, prefix)
+	}
+	if hash != hashAPIKey(key) || strings.Contains(hash, key) {
+		t.Errorf("hash %q does not match key", hash)
+	}
+
+	other, _, _, _ := generateAPIKey()
+	if other == key {
+		t.Error("expected distinct keys")
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	s := &Server{adminKey: testAdminKey}
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		if userFromContext(r.Context()) == nil {
+			t.Error("expected user in context")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		key        string
+		wantStatus int
+	}{
+		{name: "admin route without key", handler: s.requireAdmin(ok), wantStatus: http.StatusUnauthorized},
+		{name: "admin route with malformed key", handler: s.requireAdmin(ok), key: "not-a-key", wantStatus: http.StatusUnauthorized},
+		{name: "admin route with admin key", handler: s.requireAdmin(ok), key: testAdminKey, wantStatus: http.StatusNoContent},
+		{name: "user route with admin key", handler: s.requireUser(ok), key: testAdminKey, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/users", nil)
+			if tt.key != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.key)
+			}
+			rec := httptest.NewRecorder()
+			tt.handler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d; got %d", tt.wantStatus, rec.Code)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected WWW-Authenticate header")
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	corsAllowMethods  = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+	corsAllowHeaders  = "Accept, Authorization, Content-Type, API-Version, If-Match, X-API-Key, X-Request-ID, Client-Cache"
+	corsExposeHeaders = "API-Version, Deprecation, ETag, Link, Quota-Limit, Quota-Remaining, Quota-Reset, RateLimit-Limit, RateLimit-Policy, RateLimit-Remaining, RateLimit-Reset, Retry-After, WWW-Authenticate, X-Request-ID"
+	corsMaxAge        = "600"
+)
+
+// corsPolicy decides which browser origins may call the API. Origins are
+// matched exactly after normalisation, so extensions are listed as
+// chrome-extension://<id> or moz-extension://<id>.
+type corsPolicy struct {
+	origins     map[string]bool
+	allowAll    bool
+	credentials bool
+}
+
+// newCORSPolicy returns a policy for the given origins, skipping and logging
+// entries that are not valid origins
+func newCORSPolicy(origins []string, credentials bool) corsPolicy {
+	policy := corsPolicy{origins: map[string]bool{}, credentials: credentials}
+	for _, origin := range origins {
+		origin = strings.TrimSpace(origin)
+		switch {
+		case origin == "":
+		case origin == "*":
+			policy.allowAll = true
+		default:
+			normalized, ok := normalizeOrigin(origin)
+			if !ok {
+				slog.Warn("ignoring invalid cors origin", "origin", origin)
+				continue
+			}
+			policy.origins[normalized] = true
+		}
+	}
+	return policy
+}
+
+// normalizeOrigin reduces origin to lower-case scheme://host[:port]
+func normalizeOrigin(origin string) (string, bool) {
+	u, err := url.Parse(strings.TrimSuffix(origin, "/"))
+	if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+		return "", false
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host), true
+}
+
+// allows reports whether origin may call the API
+func (p corsPolicy) allows(origin string) bool {
+	if p.allowAll {
+		return true
+	}
+	normalized, ok := normalizeOrigin(origin)
+	return ok && p.origins[normalized]
+}
+
+// list returns the configured origins for logging
+func (p corsPolicy) list() []string {
+	if p.allowAll {
+		return []string{"*"}
+	}
+	list := make([]string, 0, len(p.origins))
+	for origin := range p.origins {
+		list = append(list, origin)
+	}
+	if len(list) == 0 {
+		return []string{"(none)"}
+	}
+	return list
+}
+
+// corsMiddleware echoes allowed origins back to the browser and rejects
+// cross-origin requests from any other origin. Requests without an Origin
+// header, such as curl or server-to-server calls, are not affected.
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !s.cors.allows(origin) {
+			slog.WarnContext(r.Context(), "cors origin rejected", "origin", origin, "method", r.Method, "path", r.URL.Path)
+			writeError(w, r, codeForbidden, "Origin "+origin+" is not allowed", nil)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Expose-Headers", corsExposeHeaders)
+		if s.cors.credentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		// Handle preflight OPTIONS requests
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Add("Vary", "Access-Control-Request-Method, Access-Control-Request-Headers")
+			w.Header().Set("Access-Control-Allow-Methods", corsAllowMethods)
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
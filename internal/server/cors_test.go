@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	s := &Server{cors: newCORSPolicy([]string{
+		"https://www.upwork.com/",
+		"chrome-extension://abcdefghijklmnop",
+		"not an origin",
+	}, true)}
+	handler := s.corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		method     string
+		origin     string
+		wantStatus int
+		wantOrigin string
+	}{
+		{name: "no origin", method: http.MethodGet, wantStatus: http.StatusOK},
+		{name: "exact origin", method: http.MethodGet, origin: "https://www.upwork.com", wantStatus: http.StatusOK, wantOrigin: "https://www.upwork.com"},
+		{name: "origin case", method: http.MethodGet, origin: "HTTPS://WWW.UPWORK.COM", wantStatus: http.StatusOK, wantOrigin: "HTTPS://WWW.UPWORK.COM"},
+		{name: "extension", method: http.MethodPost, origin: "chrome-extension://abcdefghijklmnop", wantStatus: http.StatusOK, wantOrigin: "chrome-extension://abcdefghijklmnop"},
+		{name: "preflight", method: http.MethodOptions, origin: "https://www.upwork.com", wantStatus: http.StatusNoContent, wantOrigin: "https://www.upwork.com"},
+		{name: "other origin", method: http.MethodGet, origin: "https://evil.example", wantStatus: http.StatusForbidden},
+		{name: "lookalike origin", method: http.MethodGet, origin: "https://www.upwork.com.evil.example", wantStatus: http.StatusForbidden},
+		{name: "other extension", method: http.MethodOptions, origin: "chrome-extension://zzzz", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/profile", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.method == http.MethodOptions {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d; got %d", tt.wantStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("expected allowed origin %q; got %q", tt.wantOrigin, got)
+			}
+			if rec.Header().Get("Vary") == "" {
+				t.Error("expected Vary header")
+			}
+			if tt.wantOrigin != "" && rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+				t.Error("expected credentials to be allowed")
+			}
+		})
+	}
+}
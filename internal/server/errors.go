@@ -0,0 +1,175 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"upwork-buddy/internal/tracing"
+)
+
+// errorCode is the machine-readable "code" of an error response. The full
+// catalogue with statuses and client guidance lives in API_ERRORS.md.
+type errorCode string
+
+const (
+	// Client errors
+	codeInvalidRequest     errorCode = "invalid_request"
+	codeValidationFailed   errorCode = "validation_failed"
+	codeUnauthorized       errorCode = "unauthorized"
+	codeForbidden          errorCode = "forbidden"
+	codeNotFound           errorCode = "not_found"
+	codeMethodNotAllowed   errorCode = "method_not_allowed"
+	codeConflict           errorCode = "conflict"
+	codeInvalidTransition  errorCode = "invalid_transition"
+	codeRateLimited        errorCode = "rate_limited"
+	codeQuotaExceeded      errorCode = "quota_exceeded"
+	codeUnsupportedVersion errorCode = "unsupported_api_version"
+	codePayloadTooLarge    errorCode = "payload_too_large"
+	codePreconditionFailed errorCode = "precondition_failed"
+	codePreconditionNeeded errorCode = "precondition_required"
+
+	// LLM provider errors
+	codeLLMNotConfigured errorCode = "llm_not_configured"
+	codeLLMUnavailable   errorCode = "llm_unavailable"
+	codeLLMBlocked       errorCode = "llm_blocked"
+	codeLLMRecitation    errorCode = "llm_recitation"
+	codeLLMTruncated     errorCode = "llm_truncated"
+	codeLLMEmpty         errorCode = "llm_empty"
+
+	// Storage errors
+	codeDatabaseUnavailable errorCode = "database_unavailable"
+	codeDatabaseError       errorCode = "database_error"
+
+	// Server state
+	codeShuttingDown   errorCode = "shutting_down"
+	codeRequestTimeout errorCode = "request_timeout"
+
+	// Anything else
+	codeInternal errorCode = "internal_error"
+)
+
+// errorSpec fixes the HTTP status and retry guidance for each code so every
+// handler reports the same failure the same way
+type errorSpec struct {
+	status    int
+	retryable bool
+}
+
+var errorCatalogue = map[errorCode]errorSpec{
+	codeInvalidRequest:      {http.StatusBadRequest, false},
+	codeValidationFailed:    {http.StatusUnprocessableEntity, false},
+	codeUnauthorized:        {http.StatusUnauthorized, false},
+	codeForbidden:           {http.StatusForbidden, false},
+	codeNotFound:            {http.StatusNotFound, false},
+	codeMethodNotAllowed:    {http.StatusMethodNotAllowed, false},
+	codeConflict:            {http.StatusConflict, false},
+	codeInvalidTransition:   {http.StatusConflict, false},
+	codeRateLimited:         {http.StatusTooManyRequests, true},
+	codeQuotaExceeded:       {http.StatusTooManyRequests, true},
+	codeUnsupportedVersion:  {http.StatusNotAcceptable, false},
+	codePayloadTooLarge:     {http.StatusRequestEntityTooLarge, false},
+	codePreconditionFailed:  {http.StatusPreconditionFailed, false},
+	codePreconditionNeeded:  {http.StatusPreconditionRequired, false},
+	codeLLMNotConfigured:    {http.StatusServiceUnavailable, false},
+	codeLLMUnavailable:      {http.StatusBadGateway, true},
+	codeLLMBlocked:          {http.StatusUnprocessableEntity, false},
+	codeLLMRecitation:       {http.StatusUnprocessableEntity, true},
+	codeLLMTruncated:        {http.StatusBadGateway, true},
+	codeLLMEmpty:            {http.StatusBadGateway, true},
+	codeDatabaseUnavailable: {http.StatusServiceUnavailable, true},
+	codeDatabaseError:       {http.StatusInternalServerError, false},
+	codeShuttingDown:        {http.StatusServiceUnavailable, true},
+	codeRequestTimeout:      {http.StatusGatewayTimeout, true},
+	codeInternal:            {http.StatusInternalServerError, false},
+}
+
+// errorBody is the JSON body of every error response:
+//
+//	{"error": {"code": "...", "message": "...", "details": ..., "request_id": "...", "trace_id": "...", "retryable": false}}
+type errorBody struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Code      errorCode   `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+	TraceID   string      `json:"trace_id,omitempty"`
+	Retryable bool        `json:"retryable"`
+}
+
+// writeError sends a JSON error envelope with the status registered for code.
+// A server-side failure after the route deadline is reported as
+// request_timeout, whichever layer gave up first.
+func writeError(w http.ResponseWriter, r *http.Request, code errorCode, message string, details interface{}) {
+	spec, ok := errorCatalogue[code]
+	if !ok {
+		spec = errorCatalogue[codeInternal]
+	}
+	if spec.status >= http.StatusInternalServerError && code != codeRequestTimeout && timedOut(r) {
+		slog.WarnContext(r.Context(), "request deadline exceeded", "code", code)
+		code, message, details = codeRequestTimeout, "The request took too long. Please try again.", nil
+		spec = errorCatalogue[code]
+	}
+
+	body := errorBody{Error: errorDetail{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: requestIDFromContext(r.Context()),
+		TraceID:   tracing.TraceID(r.Context()),
+		Retryable: spec.retryable,
+	}}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(spec.status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode error response", "error", err)
+	}
+}
+
+// jsonMuxErrors replaces the plain-text 404 and 405 responses produced by the
+// mux for unmatched requests with JSON error envelopes
+func jsonMuxErrors(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		capture := &captureWriter{header: http.Header{}, status: http.StatusOK}
+		mux.ServeHTTP(capture, r)
+
+		switch capture.status {
+		case http.StatusNotFound:
+			writeError(w, r, codeNotFound, "No route matches "+r.URL.Path, nil)
+		case http.StatusMethodNotAllowed:
+			allow := capture.header.Get("Allow")
+			w.Header().Set("Allow", allow)
+			writeError(w, r, codeMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path,
+				map[string]string{"allow": allow})
+		default:
+			// Redirects and anything else are passed through untouched
+			for key, values := range capture.header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(capture.status)
+			w.Write(capture.body.Bytes())
+		}
+	})
+}
+
+// captureWriter buffers a response so it can be inspected before sending
+type captureWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (c *captureWriter) Header() http.Header         { return c.header }
+func (c *captureWriter) Write(b []byte) (int, error) { return c.body.Write(b) }
+func (c *captureWriter) WriteHeader(status int)      { c.status = status }
@@ -1,65 +1,225 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	dbservice "upwork-buddy/internal/database/service"
 	"upwork-buddy/internal/gemini"
+	"upwork-buddy/internal/metrics"
 
 	"gorm.io/gorm"
 )
 
-// analyzeJobHandler handles POST /api/analyze-job requests
+// analyzeJobHandler handles POST /api/v1/analyze-job requests
 func (s *Server) analyzeJobHandler(w http.ResponseWriter, r *http.Request) {
-	log.Printf("=== ANALYZE JOB REQUEST START ===")
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	ctx := r.Context()
+	countClientCache(r)
+
+	var req gemini.JobAnalysisRequest
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	var req gemini.JobAnalysisRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("❌ Invalid request body: %v", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if violations := validateAnalysisRequest(req); len(violations) > 0 {
+		slog.InfoContext(ctx, "invalid analysis request", "violations", len(violations))
+		writeError(w, r, codeValidationFailed, "The request has invalid fields", violations)
 		return
 	}
 
-	log.Printf("📥 Received request: title=%q, budget=%q, skills=%q, profile_length=%d, user_skills_length=%d",
-		req.JobTitle, req.Budget, req.Skills, len(req.UserProfile), len(req.UserSkills))
+	slog.InfoContext(ctx, "analysis requested",
+		"title", req.JobTitle,
+		"budget", req.Budget,
+		"skills", req.Skills,
+		"description_length", len(req.JobDescription),
+		"profile_length", len(req.UserProfile),
+		"user_skills_length", len(req.UserSkills))
 
-	// Create Gemini service
-	geminiService, err := gemini.New()
-	if err != nil {
-		log.Printf("❌ Failed to create Gemini service: %v", err)
-		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+	if s.llm == nil {
+		slog.WarnContext(ctx, "llm provider unavailable", "error", s.llmErr)
+		writeError(w, r, codeLLMNotConfigured, "Job analysis is disabled: the server has no valid GEMINI_API_KEY", nil)
+		return
+	}
+
+	// revisionID names the stored profile revision the analysis uses
+	var revisionID *uint
+	if strings.TrimSpace(req.UserProfile) == "" {
+		var err error
+		if revisionID, err = s.applyStoredProfile(ctx, &req); err != nil {
+			slog.ErrorContext(ctx, "failed to load stored profile", "error", err)
+			writeError(w, r, codeDatabaseError, "Failed to load profile", nil)
+			return
+		}
+	}
+
+	// Shutdown waits for the analysis; once it has begun new ones are
+	// refused so another instance can take them
+	done, ok := s.lifecycle.Track()
+	if !ok {
+		w.Header().Set("Connection", "close")
+		writeError(w, r, codeShuttingDown, "The server is shutting down. Please try again.", nil)
+		return
+	}
+	defer done()
+
+	refund, ok := s.reserveAnalysis(w, r)
+	if !ok {
+		slog.WarnContext(ctx, "daily analysis quota not available")
 		return
 	}
-	defer geminiService.Close()
 
 	// Analyze the job
-	result, err := geminiService.AnalyzeJob(r.Context(), req)
+	result, err := s.llm.AnalyzeJob(ctx, req)
 	if err != nil {
-		log.Printf("❌ Failed to analyze job: %v", err)
-		http.Error(w, "Failed to analyze job", http.StatusInternalServerError)
+		slog.ErrorContext(ctx, "failed to analyze job", "error", err)
+		refund()
+		if s.lifecycle.Stopping() && ctx.Err() != nil {
+			// Cancelled because shutdown did not wait any longer
+			writeError(w, r, codeShuttingDown, "The server is shutting down. Please try again.", nil)
+			return
+		}
+		code, message, details := analysisError(err)
+		writeError(w, r, code, message, details)
 		return
 	}
 
-	log.Printf("✅ Analysis complete: proposal_length=%d, spec_sheet_length=%d, questions=%d, tips=%d",
-		len(result.Proposal), len(result.SpecSheetPrompt),
-		len(result.QuestionsForClient), len(result.TipsAndAdvice))
+	slog.InfoContext(ctx, "analysis complete",
+		"proposal_length", len(result.Proposal),
+		"spec_sheet_length", len(result.SpecSheetPrompt),
+		"questions", len(result.QuestionsForClient),
+		"tips", len(result.TipsAndAdvice),
+		"partial", result.Partial)
 
-	// Return the response
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(result); err != nil {
-		log.Printf("❌ Failed to encode response: %v", err)
-	} else {
-		log.Printf("📤 Response sent successfully")
+	s.recordAnalysis(ctx, req, revisionID, result)
+	respondWithJSON(w, result)
+}
+
+// countClientCache counts the Client-Cache header of an analysis request.
+// The bookmarklet sends hit when it already holds an analysis of the job, so
+// the hit ratio shows how many LLM calls a cache lookup would have saved.
+func countClientCache(r *http.Request) {
+	switch result := r.Header.Get("Client-Cache"); result {
+	case "hit", "miss":
+		metrics.CacheLookups.WithLabelValues("client", result).Inc()
+	}
+}
+
+// recordAnalysis stores a completed analysis of the caller with the profile
+// revision it used. A failure is only logged; the caller still gets the
+// analysis.
+func (s *Server) recordAnalysis(ctx context.Context, req gemini.JobAnalysisRequest, revisionID *uint, result *gemini.JobAnalysisResponse) {
+	user := userFromContext(ctx)
+	if user == nil || user.ID == 0 {
+		return
+	}
+	body, err := json.Marshal(result)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to encode analysis", "error", err)
+		return
+	}
+
+	// The request may already be past its deadline
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	analysis := dbservice.Analysis{
+		UserID:            user.ID,
+		ProfileRevisionID: revisionID,
+		JobTitle:          req.JobTitle,
+		Result:            string(body),
+		Partial:           result.Partial,
+	}
+	if err := s.db.GetGorm().WithContext(ctx).Create(&analysis).Error; err != nil {
+		slog.ErrorContext(ctx, "failed to store analysis", "error", err)
+		return
+	}
+	slog.DebugContext(ctx, "analysis stored", "analysis_id", analysis.ID, "profile_revision_id", revisionID)
+}
+
+// applyStoredProfile fills the profile fields of req from the caller's saved
+// profile and returns the id of the revision it used. Requests without a
+// caller or saved profile are left unchanged.
+func (s *Server) applyStoredProfile(ctx context.Context, req *gemini.JobAnalysisRequest) (*uint, error) {
+	user := userFromContext(ctx)
+	if user == nil {
+		return nil, nil
+	}
+	profile, err := s.loadProfile(ctx, user.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Revisions are immutable, so the one of the loaded version matches it
+	// even if the profile changes meanwhile
+	var revisions []uint
+	err = s.db.GetGorm().WithContext(ctx).
+		Model(&dbservice.ProfileRevision{}).
+		Where("profile_id = ? AND version = ?", profile.ID, profile.Version).
+		Pluck("id", &revisions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	req.UserProfile = profileText(profile)
+	if strings.TrimSpace(req.UserSkills) == "" {
+		req.UserSkills = profile.Skills
+	}
+	slog.DebugContext(ctx, "using stored profile",
+		"user_id", user.ID, "profile_length", len(req.UserProfile), "portfolio_items", len(profile.PortfolioItems))
+	if len(revisions) == 0 {
+		return nil, nil
+	}
+	return &revisions[0], nil
+}
+
+// profileText renders a stored profile as the free text the prompt expects,
+// listing portfolio items after the description
+func profileText(profile *dbservice.Profile) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(profile.Description))
+	if len(profile.PortfolioItems) > 0 {
+		b.WriteString("\n\nPortfolio:")
+		for _, item := range profile.PortfolioItems {
+			b.WriteString("\n- " + strings.TrimSpace(item.Title))
+			if item.Link != "" {
+				b.WriteString(" (" + item.Link + ")")
+			}
+			if desc := strings.TrimSpace(item.Description); desc != "" {
+				b.WriteString(": " + desc)
+			}
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// analysisError maps an AnalyzeJob error to an error code, a message the
+// bookmarklet can show to the user and optional details
+func analysisError(err error) (errorCode, string, interface{}) {
+	var details interface{}
+	var respErr *gemini.ResponseError
+	if errors.As(err, &respErr) {
+		details = map[string]interface{}{"reason": respErr.Reason, "categories": respErr.Categories}
+	}
+
+	switch {
+	case errors.Is(err, gemini.ErrBlocked):
+		return codeLLMBlocked, "The job posting or profile was blocked by the AI safety filters. Remove sensitive content and try again.", details
+	case errors.Is(err, gemini.ErrRecitation):
+		return codeLLMRecitation, "The AI response was stopped because it repeated copyrighted material. Try again or rephrase the job description.", details
+	case errors.Is(err, gemini.ErrTruncated):
+		return codeLLMTruncated, "The AI response was cut off before it was complete. Try again with a shorter job description.", details
+	case errors.Is(err, gemini.ErrEmpty):
+		return codeLLMEmpty, "The AI returned an empty response. Please try again.", details
+	default:
+		return codeLLMUnavailable, "The AI provider request failed. Please try again.", nil
 	}
-	log.Printf("=== ANALYZE JOB REQUEST END ===")
 }
 
 type profileRequest struct {
@@ -68,16 +228,23 @@ type profileRequest struct {
 	PortfolioItems []portfolioItemRequest `json:"portfolio_items"`
 }
 
+// portfolioItemRequest is one portfolio item of a profile save, or the body
+// of POST /api/v1/profile/portfolio-items. ID is only read by profile saves,
+// where it names the stored item to update.
 type portfolioItemRequest struct {
+	ID          uint   `json:"id,omitempty"`
 	Title       string `json:"title"`
 	Link        string `json:"link"`
 	Description string `json:"description"`
 }
 
+// profileResponse is a stored profile. Version matches the ETag header; it
+// is 0 until the profile is first saved.
 type profileResponse struct {
 	Description    string                  `json:"description"`
 	Skills         string                  `json:"skills"`
 	PortfolioItems []portfolioItemResponse `json:"portfolio_items"`
+	Version        int                     `json:"version"`
 }
 
 type portfolioItemResponse struct {
@@ -85,141 +252,172 @@ type portfolioItemResponse struct {
 	Title       string `json:"title"`
 	Link        string `json:"link"`
 	Description string `json:"description"`
+	Position    int    `json:"position"`
 }
 
-func (s *Server) profileHandler(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		s.getProfile(w)
-	case http.MethodPost, http.MethodPut:
-		s.saveProfile(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
-}
-
-func (s *Server) getProfile(w http.ResponseWriter) {
-	db := s.db.GetGorm()
-	var profile dbservice.Profile
-	if err := db.Preload("PortfolioItems").Order("id asc").First(&profile).Error; err != nil {
+// getProfile handles GET /api/v1/profile requests for the authenticated user.
+// The ETag header carries the version to send back in If-Match when saving.
+func (s *Server) getProfile(w http.ResponseWriter, r *http.Request) {
+	profile, err := s.loadProfile(r.Context(), userFromContext(r.Context()).ID)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			w.Header().Set("ETag", profileETag(0))
 			respondWithJSON(w, profileResponse{})
 			return
 		}
-		http.Error(w, "Failed to load profile", http.StatusInternalServerError)
+		writeError(w, r, codeDatabaseError, "Failed to load profile", nil)
 		return
 	}
 
-	respondWithJSON(w, profileResponseFromModel(&profile))
+	w.Header().Set("ETag", profileETag(profile.Version))
+	respondWithJSON(w, profileResponseFromModel(profile))
+}
+
+// loadProfile returns the profile of the given user with its portfolio items
+func (s *Server) loadProfile(ctx context.Context, userID uint) (*dbservice.Profile, error) {
+	var profile dbservice.Profile
+	err := s.db.GetGorm().WithContext(ctx).
+		Preload("PortfolioItems", func(db *gorm.DB) *gorm.DB { return db.Order("position asc, id asc") }).
+		Where("user_id = ?", userID).
+		First(&profile).Error
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
 }
 
+// errStaleProfile stops a profile save whose If-Match names an old version
+var errStaleProfile = errors.New("profile changed since it was loaded")
+
+// saveProfile handles POST and PUT /api/v1/profile requests, replacing the
+// authenticated user's profile. Items carrying the id of a stored item are
+// updated in place and keep that id; see mergePortfolio.
+//
+// PUT must send the ETag of the profile it replaces in If-Match, so a save
+// based on an old copy cannot overwrite newer changes. The POST alias of
+// older clients checks If-Match only when it is sent.
 func (s *Server) saveProfile(w http.ResponseWriter, r *http.Request) {
+	match := r.Header.Get("If-Match")
+	if match == "" && r.Method == http.MethodPut {
+		writeError(w, r, codePreconditionNeeded, "Send the ETag of the profile in the If-Match header", nil)
+		return
+	}
+
 	var payload profileRequest
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, &payload) {
+		return
+	}
+	if violations := validateProfileRequest(payload); len(violations) > 0 {
+		writeError(w, r, codeValidationFailed, "The profile has invalid fields", violations)
 		return
 	}
 
-	db := s.db.GetGorm()
-	tx := db.Begin()
-	if tx.Error != nil {
-		http.Error(w, "Database unavailable", http.StatusInternalServerError)
+	profile, err := s.replaceProfile(r.Context(), userFromContext(r.Context()).ID, match, payload, nil)
+	if writeProfileError(w, r, profile, err) {
 		return
 	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-			panic(r)
-		}
-	}()
 
-	var profile dbservice.Profile
-	err := tx.Preload("PortfolioItems").Order("id asc").First(&profile).Error
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		profile = dbservice.Profile{
-			Description: payload.Description,
-			Skills:      payload.Skills,
-		}
-		if err := tx.Create(&profile).Error; err != nil {
-			tx.Rollback()
-			http.Error(w, "Failed to create profile", http.StatusInternalServerError)
-			return
+	w.Header().Set("ETag", profileETag(profile.Version))
+	respondWithJSON(w, profileResponseFromModel(profile))
+}
+
+// replaceProfile replaces the profile of the user with payload and records
+// the result as a new revision, noting restoredFrom on it. When match is set
+// it must name the current version, or errStaleProfile is returned with the
+// current profile.
+func (s *Server) replaceProfile(ctx context.Context, userID uint, match string, payload profileRequest, restoredFrom *uint) (*dbservice.Profile, error) {
+	var profile *dbservice.Profile
+	err := s.db.GetGorm().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if profile, err = lockProfile(tx, userID); err != nil {
+			return err
 		}
-	} else if err != nil {
-		tx.Rollback()
-		http.Error(w, "Failed to load profile", http.StatusInternalServerError)
-		return
-	} else {
-		profile.Description = payload.Description
-		profile.Skills = payload.Skills
-		if err := tx.Save(&profile).Error; err != nil {
-			tx.Rollback()
-			http.Error(w, "Failed to update profile", http.StatusInternalServerError)
-			return
+		var stored []dbservice.PortfolioItem
+		if err := tx.Where("profile_id = ?", profile.ID).Order("position asc, id asc").Find(&stored).Error; err != nil {
+			return err
 		}
-		if err := tx.Where("profile_id = ?", profile.ID).Delete(&dbservice.PortfolioItem{}).Error; err != nil {
-			tx.Rollback()
-			http.Error(w, "Failed to clear existing portfolio", http.StatusInternalServerError)
-			return
+		if match != "" && !ifMatch(match, profileETag(profile.Version)) {
+			profile.PortfolioItems = stored
+			return errStaleProfile
 		}
-	}
 
-	createdItems := make([]portfolioItemResponse, 0, len(payload.PortfolioItems))
-	for _, itemReq := range payload.PortfolioItems {
-		if strings.TrimSpace(itemReq.Title) == "" && strings.TrimSpace(itemReq.Link) == "" && strings.TrimSpace(itemReq.Description) == "" {
-			continue
+		profile.Description = payload.Description
+		profile.Skills = payload.Skills
+		if err := tx.Save(profile).Error; err != nil {
+			return err
 		}
-		portfolioItem := dbservice.PortfolioItem{
-			ProfileID:   profile.ID,
-			Title:       itemReq.Title,
-			Link:        itemReq.Link,
-			Description: itemReq.Description,
+		items, removed := mergePortfolio(profile.ID, stored, payload.PortfolioItems)
+		if len(removed) > 0 {
+			if err := tx.Delete(&dbservice.PortfolioItem{}, removed).Error; err != nil {
+				return err
+			}
 		}
-		if err := tx.Create(&portfolioItem).Error; err != nil {
-			tx.Rollback()
-			http.Error(w, "Failed to save portfolio items", http.StatusInternalServerError)
-			return
+		for i := range items {
+			if err := tx.Save(&items[i]).Error; err != nil {
+				return err
+			}
 		}
-		createdItems = append(createdItems, portfolioItemResponse{
-			ID:          portfolioItem.ID,
-			Title:       portfolioItem.Title,
-			Link:        portfolioItem.Link,
-			Description: portfolioItem.Description,
-		})
-	}
+		profile.PortfolioItems = items
+		return bumpProfile(tx, profile, restoredFrom)
+	})
+	return profile, err
+}
 
-	if err := tx.Commit().Error; err != nil {
-		http.Error(w, "Failed to persist profile", http.StatusInternalServerError)
-		return
+// writeProfileError writes the response for a failed profile save and
+// reports whether err was one. A stale If-Match is answered with the current
+// profile and its ETag.
+func writeProfileError(w http.ResponseWriter, r *http.Request, profile *dbservice.Profile, err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, errStaleProfile) {
+		w.Header().Set("ETag", profileETag(profile.Version))
+		writeError(w, r, codePreconditionFailed, "The profile changed since it was loaded", profileResponseFromModel(profile))
+		return true
 	}
+	writeError(w, r, codeDatabaseError, "Failed to save profile", nil)
+	return true
+}
 
-	respondWithJSON(w, profileResponse{
-		Description:    profile.Description,
-		Skills:         profile.Skills,
-		PortfolioItems: createdItems,
-	})
+// profileETag is the entity tag of a profile version
+func profileETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// ifMatch reports whether an If-Match header accepts etag. Weak tags never
+// match, and "*" matches any version.
+func ifMatch(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
 }
 
 func profileResponseFromModel(profile *dbservice.Profile) profileResponse {
 	items := make([]portfolioItemResponse, 0, len(profile.PortfolioItems))
 	for _, item := range profile.PortfolioItems {
-		items = append(items, portfolioItemResponse{
-			ID:          item.ID,
-			Title:       item.Title,
-			Link:        item.Link,
-			Description: item.Description,
-		})
+		items = append(items, portfolioItemResponseFromModel(&item))
 	}
 	return profileResponse{
 		Description:    profile.Description,
 		Skills:         profile.Skills,
 		PortfolioItems: items,
+		Version:        profile.Version,
 	}
 }
 
 func respondWithJSON(w http.ResponseWriter, payload interface{}) {
+	respondWithStatus(w, http.StatusOK, payload)
+}
+
+// respondWithStatus writes payload as JSON with a non-default status
+func respondWithStatus(w http.ResponseWriter, status int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(payload); err != nil {
-		log.Printf("Failed to encode response: %v", err)
+		slog.Error("failed to encode response", "error", err)
 	}
 }
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	dbservice "upwork-buddy/internal/database/service"
+	"upwork-buddy/internal/gemini"
+	"upwork-buddy/internal/lifecycle"
+	"upwork-buddy/internal/metrics"
+)
+
+// newReplayServer returns a Server whose Gemini calls are served from the named
+// fixture, shared with the gemini package tests
+func newReplayServer(t *testing.T, fixture string) *Server {
+	t.Helper()
+
+	mode := gemini.RecorderModeFromEnv()
+	recorder, err := gemini.NewRecorder("../gemini/testdata/"+fixture, mode)
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := recorder.Save(); err != nil {
+			t.Errorf("failed to save fixture: %v", err)
+		}
+	})
+
+	apiKey := "test-key"
+	if mode == gemini.ModeRecord {
+		apiKey = os.Getenv("GEMINI_API_KEY")
+	}
+	llm, err := gemini.New(gemini.WithAPIKey(apiKey), gemini.WithHTTPClient(recorder.Client()))
+	if err != nil {
+		t.Fatalf("failed to create Gemini service: %v", err)
+	}
+	return &Server{llm: llm}
+}
+
+func TestAnalyzeJobHandler(t *testing.T) {
+	s := newReplayServer(t, "analyze_job.json")
+
+	body := `{"job_title": "Go developer for REST API with PostgreSQL",
+		"job_description": "Build shipment tracking endpoints backed by PostgreSQL and deployed with Docker.",
+		"skills": "Go, PostgreSQL, Docker",
+		"user_profile": "Backend engineer with 8 years of Go experience.",
+		"user_skills": "Go, PostgreSQL, Docker"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze-job", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	s.analyzeJobHandler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status OK; got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected JSON content type; got %q", ct)
+	}
+
+	var result gemini.JobAnalysisResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !strings.Contains(result.Proposal, "PostgreSQL") {
+		t.Errorf("expected proposal from fixture; got %q", result.Proposal)
+	}
+	if len(result.TipsAndAdvice) != 4 {
+		t.Errorf("expected 4 tips; got %d", len(result.TipsAndAdvice))
+	}
+}
+
+func TestAnalyzeJobHandlerCountsClientCache(t *testing.T) {
+	s := &Server{}
+	for _, result := range []string{"hit", "miss", "stale"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/analyze-job", strings.NewReader("{"))
+		req.Header.Set("Client-Cache", result)
+		s.analyzeJobHandler(httptest.NewRecorder(), req)
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`upwork_buddy_cache_lookups_total{cache="client",result="hit"} 1`,
+		`upwork_buddy_cache_lookups_total{cache="client",result="miss"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics to contain %s", want)
+		}
+	}
+	if strings.Contains(body, `result="stale"`) {
+		t.Error("expected unknown Client-Cache values to be ignored")
+	}
+}
+
+func TestAnalyzeJobHandlerRejectsInvalidBody(t *testing.T) {
+	s := &Server{}
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze-job", strings.NewReader("{"))
+	rec := httptest.NewRecorder()
+
+	s.analyzeJobHandler(rec, req)
+
+	assertErrorCode(t, rec, http.StatusBadRequest, codeInvalidRequest)
+}
+
+func TestAnalyzeJobHandlerValidation(t *testing.T) {
+	s := &Server{}
+	body := `{"job_title": "", "job_description": "` + strings.Repeat("x", maxJobDescriptionLength+1) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze-job", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	s.analyzeJobHandler(rec, req)
+
+	assertErrorCode(t, rec, http.StatusUnprocessableEntity, codeValidationFailed)
+	var envelope struct {
+		Error struct {
+			Details []fieldError `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(envelope.Error.Details) != 2 {
+		t.Errorf("expected 2 violations; got %+v", envelope.Error.Details)
+	}
+}
+
+func TestSaveProfileValidation(t *testing.T) {
+	s := &Server{}
+	body := `{"description": "Go developer", "portfolio_items": [{"title": "Site", "link": "javascript:alert(1)"}]}`
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/profile", strings.NewReader(body))
+	req.Header.Set("If-Match", `"1"`)
+	rec := httptest.NewRecorder()
+
+	s.saveProfile(rec, req)
+
+	assertErrorCode(t, rec, http.StatusUnprocessableEntity, codeValidationFailed)
+	if !strings.Contains(rec.Body.String(), "portfolio_items[0].link") {
+		t.Errorf("expected link violation; got %s", rec.Body.String())
+	}
+}
+
+func TestSaveProfileRequiresIfMatch(t *testing.T) {
+	s := &Server{}
+	body := `{"description": "Go developer", "portfolio_items": [{"link": "ftp://example.com"}]}`
+
+	rec := httptest.NewRecorder()
+	s.saveProfile(rec, httptest.NewRequest(http.MethodPut, "/api/v1/profile", strings.NewReader(body)))
+	assertErrorCode(t, rec, http.StatusPreconditionRequired, codePreconditionNeeded)
+
+	// The POST alias of older clients saves without If-Match, so the
+	// request gets as far as validation
+	rec = httptest.NewRecorder()
+	s.saveProfile(rec, httptest.NewRequest(http.MethodPost, "/api/profile", strings.NewReader(body)))
+	assertErrorCode(t, rec, http.StatusUnprocessableEntity, codeValidationFailed)
+}
+
+func TestIfMatch(t *testing.T) {
+	etag := profileETag(3)
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{`"3"`, true},
+		{`"2", "3"`, true},
+		{`*`, true},
+		{`"2"`, false},
+		{`W/"3"`, false},
+		{`3`, false},
+	}
+	for _, tt := range tests {
+		if got := ifMatch(tt.header, etag); got != tt.want {
+			t.Errorf("ifMatch(%s, %s) = %v; want %v", tt.header, etag, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeJobHandlerWithoutProvider(t *testing.T) {
+	s := &Server{}
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze-job", strings.NewReader(`{"job_title": "Go API", "job_description": "Build an API"}`))
+	rec := httptest.NewRecorder()
+
+	s.analyzeJobHandler(rec, req)
+
+	assertErrorCode(t, rec, http.StatusServiceUnavailable, codeLLMNotConfigured)
+}
+
+func TestProfileText(t *testing.T) {
+	profile := &dbservice.Profile{
+		Description: "Backend engineer. ",
+		PortfolioItems: []dbservice.PortfolioItem{
+			{Title: "Tracker", Link: "https://example.com/tracker", Description: "Shipment tracking API"},
+			{Title: "CLI"},
+		},
+	}
+
+	want := "Backend engineer.\n\nPortfolio:\n- Tracker (https://example.com/tracker): Shipment tracking API\n- CLI"
+	if got := profileText(profile); got != want {
+		t.Errorf("expected %q; got %q", want, got)
+	}
+	if got := profileText(&dbservice.Profile{Description: "Only text"}); got != "Only text" {
+		t.Errorf("expected description only; got %q", got)
+	}
+}
+
+// assertErrorCode checks that rec holds a JSON error envelope with the given
+// status and code
+func assertErrorCode(t *testing.T, rec *httptest.ResponseRecorder, status int, code errorCode) {
+	t.Helper()
+
+	if rec.Code != status {
+		t.Errorf("expected status %d; got %d", status, rec.Code)
+	}
+	var body errorBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("expected JSON error envelope; got %q", rec.Body.String())
+	}
+	if body.Error.Code != code {
+		t.Errorf("expected error code %q; got %q", code, body.Error.Code)
+	}
+	if body.Error.Message == "" {
+		t.Error("expected error message")
+	}
+}
+
+func TestAnalyzeJobHandlerShuttingDown(t *testing.T) {
+	s := newReplayServer(t, "analyze_job.json")
+	s.lifecycle = lifecycle.New(time.Second)
+	if err := s.lifecycle.Shutdown(); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+
+	body := `{"job_title": "Go developer", "job_description": "Build an API.", "user_profile": "Go engineer."}`
+	rec := httptest.NewRecorder()
+	s.analyzeJobHandler(rec, httptest.NewRequest(http.MethodPost, "/api/analyze-job", strings.NewReader(body)))
+
+	assertErrorCode(t, rec, http.StatusServiceUnavailable, codeShuttingDown)
+
+	rec = httptest.NewRecorder()
+	s.readyzHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected readyz to fail while shutting down; got %d", rec.Code)
+	}
+}
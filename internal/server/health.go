@@ -0,0 +1,206 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"upwork-buddy/internal/gemini"
+)
+
+// Check and readiness states reported by /readyz
+const (
+	checkUp   = "up"
+	checkDown = "down"
+
+	statusReady    = "ready"
+	statusDegraded = "degraded"
+	statusNotReady = "not_ready"
+)
+
+const (
+	// checkTimeout bounds each dependency check so a hung database cannot
+	// hang the probe
+	checkTimeout = 2 * time.Second
+
+	// llmProbeTTL limits how often /readyz calls the LLM provider, which
+	// counts against the API quota
+	llmProbeTTL = 30 * time.Second
+)
+
+type livenessResponse struct {
+	Status string `json:"status"`
+}
+
+// readinessResponse is the body of /readyz. The server is not ready when a
+// critical check is down and degraded when only optional ones are.
+type readinessResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+type checkResult struct {
+	Status    string            `json:"status"`
+	Critical  bool              `json:"critical"`
+	Error     string            `json:"error,omitempty"`
+	LatencyMS int64             `json:"latency_ms"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+// livezHandler handles GET /livez. It only shows that the process serves
+// requests and never looks at dependencies, so orchestrators do not restart
+// the server because the database is down.
+func (s *Server) livezHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, livenessResponse{Status: "alive"})
+}
+
+// readyzHandler handles GET /readyz and the older GET /health. It answers
+// 503 while a critical dependency is unavailable.
+func (s *Server) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	report := s.readiness(r.Context())
+	status := http.StatusOK
+	if report.Status == statusNotReady {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithStatus(w, status, report)
+}
+
+// readiness runs every dependency check concurrently. Once shutdown has
+// begun the server is not ready, so load balancers stop sending traffic
+// while in-flight requests drain.
+func (s *Server) readiness(ctx context.Context) readinessResponse {
+	if s.lifecycle.Stopping() {
+		return readinessResponse{Status: statusNotReady, Checks: map[string]checkResult{}}
+	}
+
+	checks := map[string]func(context.Context) checkResult{
+		"database":   s.checkDatabase,
+		"migrations": s.checkMigrations,
+		"llm":        s.checkLLM,
+	}
+
+	report := readinessResponse{Status: statusReady, Checks: map[string]checkResult{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			started := time.Now()
+			result := check(ctx)
+			result.LatencyMS = time.Since(started).Milliseconds()
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+		}()
+	}
+	wg.Wait()
+
+	for name, result := range report.Checks {
+		if result.Status == checkUp {
+			continue
+		}
+		slog.WarnContext(ctx, "readiness check failed", "check", name, "error", result.Error)
+		if result.Critical {
+			report.Status = statusNotReady
+		} else if report.Status == statusReady {
+			report.Status = statusDegraded
+		}
+	}
+	return report
+}
+
+// checkDatabase pings the database and reports its connection pool
+func (s *Server) checkDatabase(ctx context.Context) checkResult {
+	result := checkResult{Status: checkUp, Critical: true}
+	if s.db == nil {
+		return down(result, "database is not configured")
+	}
+
+	stats := s.db.Health(ctx)
+	if stats["status"] != "up" {
+		// The driver error names hosts and users, so it is only logged
+		slog.WarnContext(ctx, "database unavailable", "error", stats["error"])
+		return down(result, "database ping failed")
+	}
+	result.Details = map[string]string{}
+	for key, value := range stats {
+		if key != "status" {
+			result.Details[key] = value
+		}
+	}
+	return result
+}
+
+// checkMigrations reports migrations that have not been applied yet; the
+// handlers would fail on the missing tables and columns
+func (s *Server) checkMigrations(ctx context.Context) checkResult {
+	result := checkResult{Status: checkUp, Critical: true}
+	if s.db == nil {
+		return down(result, "database is not configured")
+	}
+
+	pending, err := s.db.PendingMigrations(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to check migrations", "error", err)
+		return down(result, "failed to read applied migrations")
+	}
+	if len(pending) > 0 {
+		result = down(result, strconv.Itoa(len(pending))+" migrations are not applied")
+		result.Details = map[string]string{"pending": strings.Join(pending, ", ")}
+	}
+	return result
+}
+
+// checkLLM reports whether the LLM provider is configured and accepts the
+// API key. Without it only job analysis is unavailable, so the check is
+// critical only when GEMINI_REQUIRED is set.
+func (s *Server) checkLLM(ctx context.Context) checkResult {
+	result := checkResult{Status: checkUp, Critical: s.llmRequired}
+	if s.llm == nil {
+		return down(result, "llm provider is not configured")
+	}
+
+	if err := s.llmProbe.check(ctx, s.llm.Validate); err != nil {
+		return down(result, fmt.Sprintf("llm provider check failed (%s)", gemini.ErrorClass(err)))
+	}
+	result.Details = map[string]string{"model": s.llm.Model()}
+	return result
+}
+
+func down(result checkResult, message string) checkResult {
+	result.Status = checkDown
+	result.Error = message
+	return result
+}
+
+// probeCache remembers the outcome of a check for llmProbeTTL. Concurrent
+// callers wait for a single probe instead of each calling the provider.
+type probeCache struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	err       error
+}
+
+func (p *probeCache) check(ctx context.Context, probe func(context.Context) error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.checkedAt.IsZero() && time.Since(p.checkedAt) < llmProbeTTL {
+		return p.err
+	}
+	p.err = probe(ctx)
+	p.checkedAt = time.Now()
+	return p.err
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// fakeDB reports a fixed health without a real database
+type fakeDB struct {
+	up         bool
+	pending    []string
+	pendingErr error
+}
+
+func (f *fakeDB) Health(ctx context.Context) map[string]string {
+	if !f.up {
+		return map[string]string{"status": "down", "error": "db down: dial tcp 10.0.0.5:5432: connection refused"}
+	}
+	return map[string]string{"status": "up", "open_connections": "1"}
+}
+
+func (f *fakeDB) PendingMigrations(ctx context.Context) ([]string, error) {
+	return f.pending, f.pendingErr
+}
+
+func (f *fakeDB) Close() error      { return nil }
+func (f *fakeDB) GetDB() *sql.DB    { return nil }
+func (f *fakeDB) GetGorm() *gorm.DB { return nil }
+
+func TestLivez(t *testing.T) {
+	// A server whose database is down is still alive
+	s := &Server{db: &fakeDB{}}
+	rec := httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status OK; got %d", rec.Code)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	doc := loadOpenAPI(t)
+
+	tests := []struct {
+		name       string
+		server     *Server
+		wantStatus int
+		wantReport string
+		wantDown   []string
+	}{
+		{
+			name:       "optional llm missing",
+			server:     &Server{db: &fakeDB{up: true}},
+			wantStatus: http.StatusOK,
+			wantReport: statusDegraded,
+			wantDown:   []string{"llm"},
+		},
+		{
+			name:       "required llm missing",
+			server:     &Server{db: &fakeDB{up: true}, llmRequired: true},
+			wantStatus: http.StatusServiceUnavailable,
+			wantReport: statusNotReady,
+			wantDown:   []string{"llm"},
+		},
+		{
+			name:       "database down",
+			server:     &Server{db: &fakeDB{pendingErr: errors.New("connection refused")}},
+			wantStatus: http.StatusServiceUnavailable,
+			wantReport: statusNotReady,
+			wantDown:   []string{"database", "migrations", "llm"},
+		},
+		{
+			name:       "migrations pending",
+			server:     &Server{db: &fakeDB{up: true, pending: []string{"20261018140000"}}},
+			wantStatus: http.StatusServiceUnavailable,
+			wantReport: statusNotReady,
+			wantDown:   []string{"migrations", "llm"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.server.RegisterRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d; got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			var report readinessResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+				t.Fatalf("failed to decode report: %v", err)
+			}
+			if report.Status != tt.wantReport {
+				t.Errorf("expected status %q; got %q", tt.wantReport, report.Status)
+			}
+			for name, check := range report.Checks {
+				wantDown := false
+				for _, down := range tt.wantDown {
+					wantDown = wantDown || down == name
+				}
+				if (check.Status == checkDown) != wantDown {
+					t.Errorf("expected %s down=%v; got %+v", name, wantDown, check)
+				}
+			}
+			if len(report.Checks) != 3 {
+				t.Errorf("expected 3 checks; got %v", report.Checks)
+			}
+
+			var body interface{}
+			json.Unmarshal(rec.Body.Bytes(), &body)
+			for _, violation := range validateSchema(doc, responseSchema(doc, http.MethodGet, "/readyz", rec.Code), body, "$") {
+				t.Error(violation)
+			}
+		})
+	}
+}
+
+func TestReadyzHidesDriverErrors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	(&Server{db: &fakeDB{}}).readyzHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var report readinessResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+	if got := report.Checks["database"].Error; got != "database ping failed" {
+		t.Errorf("expected generic database error; got %q", got)
+	}
+}
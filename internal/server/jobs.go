@@ -0,0 +1,541 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	dbservice "upwork-buddy/internal/database/service"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultJobSource = "upwork"
+
+	// Page sizes of GET /api/v1/jobs
+	defaultJobPageSize = 50
+	maxJobPageSize     = 200
+)
+
+// jobRequest is the body of POST and PATCH /api/v1/jobs. PATCH changes only
+// the fields that are present; a new status must be allowed by the pipeline.
+type jobRequest struct {
+	Title       *string    `json:"title"`
+	Description *string    `json:"description"`
+	URL         *string    `json:"url"`
+	Source      *string    `json:"source"`
+	Status      *string    `json:"status"`
+	AppliedAt   *time.Time `json:"applied_at"`
+}
+
+type jobResponse struct {
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	URL         string     `json:"url"`
+	Source      string     `json:"source"`
+	Status      string     `json:"status"`
+	AppliedAt   *time.Time `json:"applied_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	// NextStatuses lists the stages the job can move to
+	NextStatuses []string `json:"next_statuses"`
+}
+
+// jobTransitionRequest is the body of POST /api/v1/jobs/{id}/transitions
+type jobTransitionRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+type jobTransitionResponse struct {
+	ID         uint      `json:"id"`
+	FromStatus *string   `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	Note       string    `json:"note"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// jobListResponse is one page of jobs. next_cursor is empty on the last page.
+type jobListResponse struct {
+	Jobs       []jobResponse `json:"jobs"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// listJobs handles GET /api/v1/jobs, returning the caller's jobs filtered,
+// sorted and paginated by the query parameters
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request) {
+	query, violations := parseJobQuery(r.URL.Query())
+	if len(violations) > 0 {
+		writeError(w, r, codeValidationFailed, "The query has invalid parameters", violations)
+		return
+	}
+
+	db := query.apply(s.db.GetGorm().WithContext(r.Context()).Where("user_id = ?", userFromContext(r.Context()).ID))
+	var jobs []dbservice.Job
+	// One extra row tells whether there is a next page
+	if err := db.Limit(query.limit + 1).Find(&jobs).Error; err != nil {
+		writeError(w, r, codeDatabaseError, "Failed to load jobs", nil)
+		return
+	}
+
+	resp := jobListResponse{Jobs: make([]jobResponse, 0, len(jobs))}
+	if len(jobs) > query.limit {
+		jobs = jobs[:query.limit]
+		resp.NextCursor = query.sort.cursorAfter(&jobs[len(jobs)-1])
+	}
+	for i := range jobs {
+		resp.Jobs = append(resp.Jobs, jobResponseFromModel(&jobs[i]))
+	}
+	respondWithJSON(w, resp)
+}
+
+// createJob handles POST /api/v1/jobs
+func (s *Server) createJob(w http.ResponseWriter, r *http.Request) {
+	var payload jobRequest
+	if !decodeJSON(w, r, &payload) {
+		return
+	}
+	if violations := validateJobRequest(payload, true); len(violations) > 0 {
+		writeError(w, r, codeValidationFailed, "The job has invalid fields", violations)
+		return
+	}
+
+	user := userFromContext(r.Context())
+	job := dbservice.Job{UserID: &user.ID, Source: defaultJobSource, Status: stageDiscovered}
+	payload.applyTo(&job)
+	// A job can be recorded at any stage, e.g. one applied to before it
+	// was tracked; that stage starts its history
+	if payload.Status != nil {
+		job.Status = normalizeStage(*payload.Status)
+	}
+	if job.Status == stageApplied && job.AppliedAt == nil {
+		now := time.Now()
+		job.AppliedAt = &now
+	}
+	err := s.db.GetGorm().WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&job).Error; err != nil {
+			return err
+		}
+		return tx.Create(&dbservice.JobTransition{JobID: job.ID, ToStatus: job.Status}).Error
+	})
+	if err != nil {
+		writeError(w, r, codeDatabaseError, "Failed to create job", nil)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "job created", "job_id", job.ID, "status", job.Status)
+	w.Header().Set("Location", "/api/v1/jobs/"+strconv.FormatUint(uint64(job.ID), 10))
+	respondWithStatus(w, http.StatusCreated, jobResponseFromModel(&job))
+}
+
+// getJob handles GET /api/v1/jobs/{id}
+func (s *Server) getJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.loadJob(w, r)
+	if !ok {
+		return
+	}
+	respondWithJSON(w, jobResponseFromModel(job))
+}
+
+// updateJob handles PATCH /api/v1/jobs/{id}
+func (s *Server) updateJob(w http.ResponseWriter, r *http.Request) {
+	var payload jobRequest
+	if !decodeJSON(w, r, &payload) {
+		return
+	}
+	if violations := validateJobRequest(payload, false); len(violations) > 0 {
+		writeError(w, r, codeValidationFailed, "The job has invalid fields", violations)
+		return
+	}
+
+	job, ok := s.loadJob(w, r)
+	if !ok {
+		return
+	}
+	err := s.db.GetGorm().WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := lockJob(tx, job); err != nil {
+			return err
+		}
+		payload.applyTo(job)
+		if payload.Status != nil && normalizeStage(*payload.Status) != job.Status {
+			return moveJob(tx, job, normalizeStage(*payload.Status), "")
+		}
+		return tx.Save(job).Error
+	})
+	if writeTransitionError(w, r, err, "Failed to update job") {
+		return
+	}
+
+	slog.InfoContext(r.Context(), "job updated", "job_id", job.ID, "status", job.Status)
+	respondWithJSON(w, jobResponseFromModel(job))
+}
+
+// transitionJob handles POST /api/v1/jobs/{id}/transitions, moving a job to
+// another pipeline stage
+func (s *Server) transitionJob(w http.ResponseWriter, r *http.Request) {
+	var payload jobTransitionRequest
+	if !decodeJSON(w, r, &payload) {
+		return
+	}
+	if violations := validateJobTransitionRequest(payload); len(violations) > 0 {
+		writeError(w, r, codeValidationFailed, "The transition has invalid fields", violations)
+		return
+	}
+
+	job, ok := s.loadJob(w, r)
+	if !ok {
+		return
+	}
+	from := job.Status
+	err := s.db.GetGorm().WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := lockJob(tx, job); err != nil {
+			return err
+		}
+		from = job.Status
+		return moveJob(tx, job, normalizeStage(payload.Status), strings.TrimSpace(payload.Note))
+	})
+	if writeTransitionError(w, r, err, "Failed to move job") {
+		return
+	}
+
+	slog.InfoContext(r.Context(), "job moved", "job_id", job.ID, "from", from, "to", job.Status)
+	respondWithJSON(w, jobResponseFromModel(job))
+}
+
+// listJobTransitions handles GET /api/v1/jobs/{id}/transitions, returning
+// the stage history of a job, oldest first
+func (s *Server) listJobTransitions(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.loadJob(w, r)
+	if !ok {
+		return
+	}
+
+	var transitions []dbservice.JobTransition
+	err := s.db.GetGorm().WithContext(r.Context()).
+		Where("job_id = ?", job.ID).
+		Order("created_at asc, id asc").
+		Find(&transitions).Error
+	if err != nil {
+		writeError(w, r, codeDatabaseError, "Failed to load job history", nil)
+		return
+	}
+
+	resp := make([]jobTransitionResponse, 0, len(transitions))
+	for _, t := range transitions {
+		resp = append(resp, jobTransitionResponse{
+			ID:         t.ID,
+			FromStatus: t.FromStatus,
+			ToStatus:   t.ToStatus,
+			Note:       t.Note,
+			CreatedAt:  t.CreatedAt,
+		})
+	}
+	respondWithJSON(w, resp)
+}
+
+// lockJob reloads job and locks its row until the transaction ends, so
+// concurrent moves see each other's stage
+func lockJob(tx *gorm.DB, job *dbservice.Job) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(job, job.ID).Error
+}
+
+// writeTransitionError writes the response for a failed job update and
+// reports whether err was one. Moves the pipeline does not allow are
+// conflicts with the current stage.
+func writeTransitionError(w http.ResponseWriter, r *http.Request, err error, message string) bool {
+	if err == nil {
+		return false
+	}
+	var invalid *transitionError
+	if errors.As(err, &invalid) {
+		writeError(w, r, codeInvalidTransition, "The job cannot move from "+invalid.From+" to "+invalid.To, invalid)
+		return true
+	}
+	writeError(w, r, codeDatabaseError, message, nil)
+	return true
+}
+
+// deleteJob handles DELETE /api/v1/jobs/{id}
+func (s *Server) deleteJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.loadJob(w, r)
+	if !ok {
+		return
+	}
+	if err := s.db.GetGorm().WithContext(r.Context()).Delete(job).Error; err != nil {
+		writeError(w, r, codeDatabaseError, "Failed to delete job", nil)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "job deleted", "job_id", job.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadJob fetches the job named by the {id} path parameter. Jobs of other
+// users are reported as not found.
+func (s *Server) loadJob(w http.ResponseWriter, r *http.Request) (*dbservice.Job, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, r, codeNotFound, "Job not found", nil)
+		return nil, false
+	}
+
+	var job dbservice.Job
+	err = s.db.GetGorm().WithContext(r.Context()).
+		Where("user_id = ?", userFromContext(r.Context()).ID).
+		First(&job, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeError(w, r, codeNotFound, "Job not found", nil)
+			return nil, false
+		}
+		writeError(w, r, codeDatabaseError, "Failed to load job", nil)
+		return nil, false
+	}
+	return &job, true
+}
+
+// applyTo copies the fields present in req onto job, except the status,
+// which only changes through the pipeline
+func (req jobRequest) applyTo(job *dbservice.Job) {
+	if req.Title != nil {
+		job.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.Description != nil {
+		job.Description = *req.Description
+	}
+	if req.URL != nil {
+		job.URL = strings.TrimSpace(*req.URL)
+	}
+	if req.Source != nil {
+		job.Source = strings.ToLower(strings.TrimSpace(*req.Source))
+	}
+	if req.AppliedAt != nil {
+		job.AppliedAt = req.AppliedAt
+	}
+}
+
+func jobResponseFromModel(job *dbservice.Job) jobResponse {
+	return jobResponse{
+		ID:          job.ID,
+		Title:       job.Title,
+		Description: job.Description,
+		URL:         job.URL,
+		Source:      job.Source,
+		Status:      job.Status,
+		AppliedAt:   job.AppliedAt,
+		CreatedAt:   job.CreatedAt,
+		UpdatedAt:   job.UpdatedAt,
+
+		NextStatuses: jobTransitions[job.Status],
+	}
+}
+
+// normalizeStage trims and lowercases a stage name sent by a client
+func normalizeStage(stage string) string {
+	return strings.ToLower(strings.TrimSpace(stage))
+}
+
+// jobSort is a sort order of the job list. Every order ends with the ID so
+// the cursor of a page identifies a single row.
+type jobSort struct {
+	// name is the value of the sort parameter, "-" prefixed when descending
+	name   string
+	column string
+	desc   bool
+}
+
+// parseJobSort reads a sort parameter such as "-created_at"
+func parseJobSort(raw string) (jobSort, bool) {
+	column, desc := strings.CutPrefix(raw, "-")
+	switch column {
+	case "created_at", "updated_at", "title":
+		return jobSort{name: raw, column: column, desc: desc}, true
+	}
+	return jobSort{}, false
+}
+
+// jobCursor marks the last row of a page. It is opaque to clients.
+type jobCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+// cursorAfter encodes the position of job in this sort order
+func (o jobSort) cursorAfter(job *dbservice.Job) string {
+	c := jobCursor{Sort: o.name, ID: job.ID}
+	switch o.column {
+	case "created_at":
+		c.Value = job.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "updated_at":
+		c.Value = job.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	case "title":
+		c.Value = job.Title
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor reads a cursor produced by cursorAfter for the same order
+func (o jobSort) decodeCursor(raw string) (value interface{}, id uint, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, 0, err
+	}
+	var c jobCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, 0, err
+	}
+	if c.Sort != o.name {
+		return nil, 0, fmt.Errorf("cursor was issued for sort %q", c.Sort)
+	}
+	if o.column == "title" {
+		return c.Value, c.ID, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, c.Value)
+	return t, c.ID, err
+}
+
+// jobQuery holds the parsed query parameters of GET /api/v1/jobs
+type jobQuery struct {
+	statuses []string
+	sources  []string
+
+	createdAfter, createdBefore time.Time
+	appliedAfter, appliedBefore time.Time
+
+	sort  jobSort
+	limit int
+
+	// afterValue and afterID are set when the request continues from a
+	// cursor
+	afterValue interface{}
+	afterID    uint
+}
+
+// parseJobQuery reads the filters, sort order, page size and cursor of a
+// job list request. Dates are RFC 3339 timestamps or YYYY-MM-DD days.
+func parseJobQuery(values url.Values) (jobQuery, []fieldError) {
+	var v validator
+	q := jobQuery{
+		statuses: splitList(values.Get("status")),
+		sources:  splitList(values.Get("source")),
+		sort:     jobSort{name: "-created_at", column: "created_at", desc: true},
+		limit:    defaultJobPageSize,
+	}
+
+	for _, status := range q.statuses {
+		v.oneOf("status", status, jobStages)
+	}
+
+	dates := []struct {
+		param string
+		dest  *time.Time
+	}{
+		{"created_after", &q.createdAfter},
+		{"created_before", &q.createdBefore},
+		{"applied_after", &q.appliedAfter},
+		{"applied_before", &q.appliedBefore},
+	}
+	for _, d := range dates {
+		raw := strings.TrimSpace(values.Get(d.param))
+		if raw == "" {
+			continue
+		}
+		t, err := parseQueryTime(raw)
+		if err != nil {
+			v.add(d.param, "date", d.param+" must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+			continue
+		}
+		*d.dest = t
+	}
+
+	if raw := values.Get("sort"); raw != "" {
+		sort, ok := parseJobSort(raw)
+		if !ok {
+			v.add("sort", "one_of", "sort must be one of created_at, updated_at or title, optionally prefixed with -")
+		}
+		q.sort = sort
+	}
+
+	if raw := values.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxJobPageSize {
+			v.add("limit", "range", fmt.Sprintf("limit must be a number from 1 to %d", maxJobPageSize))
+		}
+		q.limit = n
+	}
+
+	if raw := values.Get("cursor"); raw != "" && q.sort.name != "" {
+		value, id, err := q.sort.decodeCursor(raw)
+		if err != nil {
+			v.add("cursor", "cursor", "cursor is invalid or was issued for a different sort order")
+		}
+		q.afterValue, q.afterID = value, id
+	}
+
+	return q, v.errors
+}
+
+// apply adds the filters, cursor and order of q to db
+func (q jobQuery) apply(db *gorm.DB) *gorm.DB {
+	if len(q.statuses) > 0 {
+		db = db.Where("status IN ?", q.statuses)
+	}
+	if len(q.sources) > 0 {
+		db = db.Where("source IN ?", q.sources)
+	}
+	if !q.createdAfter.IsZero() {
+		db = db.Where("created_at >= ?", q.createdAfter)
+	}
+	if !q.createdBefore.IsZero() {
+		db = db.Where("created_at < ?", q.createdBefore)
+	}
+	if !q.appliedAfter.IsZero() {
+		db = db.Where("applied_at >= ?", q.appliedAfter)
+	}
+	if !q.appliedBefore.IsZero() {
+		db = db.Where("applied_at < ?", q.appliedBefore)
+	}
+
+	// The column is one of the names accepted by parseJobSort, never user
+	// input
+	direction, compare := "ASC", ">"
+	if q.sort.desc {
+		direction, compare = "DESC", "<"
+	}
+	if q.afterValue != nil {
+		db = db.Where(fmt.Sprintf("(%s, id) %s (?, ?)", q.sort.column, compare), q.afterValue, q.afterID)
+	}
+	return db.Order(fmt.Sprintf("%s %s, id %s", q.sort.column, direction, direction))
+}
+
+// parseQueryTime accepts an RFC 3339 timestamp or a YYYY-MM-DD day, read as
+// midnight UTC
+func parseQueryTime(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, raw)
+}
+
+// splitList splits a comma separated query value, lowercasing the items
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
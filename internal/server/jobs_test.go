@@ -0,0 +1,176 @@
+package server
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	dbservice "upwork-buddy/internal/database/service"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestParseJobQuery(t *testing.T) {
+	q, violations := parseJobQuery(url.Values{
+		"status":        {"Applied, declined"},
+		"source":        {"upwork"},
+		"created_after": {"2026-10-01"},
+		"applied_after": {"2026-10-05T09:30:00+02:00"},
+		"sort":          {"title"},
+		"limit":         {"20"},
+	})
+	if len(violations) > 0 {
+		t.Fatalf("unexpected violations: %+v", violations)
+	}
+	if strings.Join(q.statuses, " ") != "applied declined" || strings.Join(q.sources, " ") != "upwork" {
+		t.Errorf("expected lowercased filters; got %v, %v", q.statuses, q.sources)
+	}
+	if !q.createdAfter.Equal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected a date to mean midnight UTC; got %s", q.createdAfter)
+	}
+	if !q.appliedAfter.Equal(time.Date(2026, 10, 5, 7, 30, 0, 0, time.UTC)) {
+		t.Errorf("expected the timestamp offset to be kept; got %s", q.appliedAfter)
+	}
+	if q.sort.column != "title" || q.sort.desc || q.limit != 20 {
+		t.Errorf("expected ascending title sort with 20 rows; got %+v, %d", q.sort, q.limit)
+	}
+
+	q, violations = parseJobQuery(url.Values{})
+	if len(violations) > 0 || q.sort.name != "-created_at" || q.limit != defaultJobPageSize {
+		t.Errorf("expected newest first with the default page size; got %+v, %d, %+v", q.sort, q.limit, violations)
+	}
+
+	_, violations = parseJobQuery(url.Values{
+		"status":         {"rejected"},
+		"created_before": {"yesterday"},
+		"sort":           {"-status"},
+		"limit":          {"500"},
+	})
+	var fields []string
+	for _, v := range violations {
+		fields = append(fields, v.Field+":"+v.Rule)
+	}
+	if got := strings.Join(fields, " "); got != "status:one_of created_before:date sort:one_of limit:range" {
+		t.Errorf("unexpected violations: %s", got)
+	}
+}
+
+func TestJobCursor(t *testing.T) {
+	job := &dbservice.Job{ID: 42, Title: "Go API", CreatedAt: time.Date(2026, 10, 18, 12, 0, 0, 123e6, time.UTC)}
+
+	sort, _ := parseJobSort("-created_at")
+	q, violations := parseJobQuery(url.Values{"cursor": {sort.cursorAfter(job)}})
+	if len(violations) > 0 {
+		t.Fatalf("unexpected violations: %+v", violations)
+	}
+	if q.afterID != 42 || !q.afterValue.(time.Time).Equal(job.CreatedAt) {
+		t.Errorf("expected the cursor to round-trip; got %v, %d", q.afterValue, q.afterID)
+	}
+
+	// A cursor only continues the order it was issued for
+	_, violations = parseJobQuery(url.Values{"cursor": {sort.cursorAfter(job)}, "sort": {"title"}})
+	if len(violations) != 1 || violations[0].Field != "cursor" {
+		t.Errorf("expected a cursor violation; got %+v", violations)
+	}
+	_, violations = parseJobQuery(url.Values{"cursor": {"not-a-cursor"}})
+	if len(violations) != 1 || violations[0].Field != "cursor" {
+		t.Errorf("expected a cursor violation; got %+v", violations)
+	}
+}
+
+// dryRunDB builds SQL statements without a database
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "postgres://localhost/test"}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestJobQuerySQL(t *testing.T) {
+	db := dryRunDB(t)
+
+	sort, _ := parseJobSort("-updated_at")
+	cursor := sort.cursorAfter(&dbservice.Job{ID: 7, UpdatedAt: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)})
+	q, _ := parseJobQuery(url.Values{"status": {"applied"}, "sort": {"-updated_at"}, "cursor": {cursor}})
+
+	var jobs []dbservice.Job
+	stmt := q.apply(db.Where("user_id = ?", 1)).Limit(q.limit + 1).Find(&jobs).Statement
+	want := `SELECT * FROM "jobs" WHERE user_id = $1 AND status IN ($2) AND (updated_at, id) < ($3, $4) ORDER BY updated_at DESC, id DESC LIMIT $5`
+	if got := stmt.SQL.String(); got != want {
+		t.Errorf("unexpected query:\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestValidateJobRequest(t *testing.T) {
+	title, empty, link := "Go API", " ", "ftp://example.com"
+
+	tests := []struct {
+		name   string
+		req    jobRequest
+		create bool
+		want   string
+	}{
+		{name: "create without title", req: jobRequest{}, create: true, want: "title:required"},
+		{name: "create", req: jobRequest{Title: &title}, create: true},
+		{name: "update without title", req: jobRequest{}},
+		{name: "update clearing title", req: jobRequest{Title: &empty}, want: "title:required"},
+		{name: "bad url", req: jobRequest{Title: &title, URL: &link}, want: "url:url"},
+		{name: "blank status", req: jobRequest{Status: &empty}, want: "status:required"},
+		{name: "unknown status", req: jobRequest{Status: &link}, want: "status:one_of"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, v := range validateJobRequest(tt.req, tt.create) {
+				got = append(got, v.Field+":"+v.Rule)
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("expected %q; got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJobPipeline(t *testing.T) {
+	for _, from := range jobStages {
+		next, ok := jobTransitions[from]
+		if !ok {
+			t.Errorf("stage %s has no transitions", from)
+		}
+		for _, to := range next {
+			if slices.Index(jobStages, to) <= slices.Index(jobStages, from) {
+				t.Errorf("%s -> %s moves a job backwards", from, to)
+			}
+		}
+	}
+}
+
+func TestAdvanceJob(t *testing.T) {
+	job := &dbservice.Job{ID: 1, Status: stageShortlisted}
+	if from, err := advanceJob(job, stageApplied); err != nil || from != stageShortlisted {
+		t.Fatalf("unexpected result: %s, %v", from, err)
+	}
+	if job.Status != stageApplied || job.AppliedAt == nil {
+		t.Errorf("expected an applied job with applied_at; got %s, %v", job.Status, job.AppliedAt)
+	}
+
+	_, err := advanceJob(job, stageAnalyzed)
+	var invalid *transitionError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected a transition error; got %v", err)
+	}
+	if invalid.From != stageApplied || !slices.Contains(invalid.Allowed, stageInterviewing) {
+		t.Errorf("unexpected transition error: %+v", invalid)
+	}
+	if job.Status != stageApplied {
+		t.Errorf("expected the job to stay applied; got %s", job.Status)
+	}
+}
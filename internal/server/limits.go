@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Body size caps. The validation limits allow up to ~35k characters in an
+// analysis request, ~23k in a job and ~225k in a profile; the caps leave
+// room for four byte UTF-8 and JSON syntax.
+const (
+	analyzeMaxBody = 256 << 10
+	jobMaxBody     = 128 << 10
+	profileMaxBody = 1 << 20
+	defaultMaxBody = 16 << 10
+)
+
+const (
+	// probeTimeout bounds /readyz, whose checks are each bounded by
+	// checkTimeout and run concurrently
+	probeTimeout = 5 * time.Second
+
+	// writeGrace keeps the connection writable past the route deadline so
+	// the 504 can still be sent
+	writeGrace = 5 * time.Second
+)
+
+// routeLimit is the deadline and body size cap of a route. A zero timeout
+// leaves the request without a deadline.
+type routeLimit struct {
+	timeout time.Duration
+	maxBody int64
+}
+
+// bounded applies limit to next. The request context gets the deadline, so
+// the LLM and database calls made with it stop when it passes, and
+// writeError then reports the failure as request_timeout. The connection
+// read and write deadlines replace the server-wide ones for this request.
+func bounded(limit routeLimit, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit.maxBody {
+			writeError(w, r, codePayloadTooLarge, "The request body is too large",
+				map[string]int64{"max_bytes": limit.maxBody})
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit.maxBody)
+
+		if limit.timeout <= 0 {
+			next(w, r)
+			return
+		}
+		deadline := time.Now().Add(limit.timeout)
+		rc := http.NewResponseController(w)
+		if err := rc.SetReadDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			slog.DebugContext(r.Context(), "failed to set read deadline", "error", err)
+		}
+		if err := rc.SetWriteDeadline(deadline.Add(writeGrace)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			slog.DebugContext(r.Context(), "failed to set write deadline", "error", err)
+		}
+
+		ctx, cancel := context.WithDeadline(r.Context(), deadline)
+		defer cancel()
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// decodeJSON decodes the request body into v. On failure it writes
+// payload_too_large when the body exceeds the route cap and
+// invalid_request otherwise, and returns false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil {
+		return true
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		slog.InfoContext(r.Context(), "request body too large", "max_bytes", tooLarge.Limit)
+		writeError(w, r, codePayloadTooLarge, "The request body is too large",
+			map[string]int64{"max_bytes": tooLarge.Limit})
+		return false
+	}
+	slog.InfoContext(r.Context(), "invalid request body", "error", err)
+	writeError(w, r, codeInvalidRequest, "Invalid request body", err.Error())
+	return false
+}
+
+// timedOut reports whether the request ran past the deadline set by bounded
+func timedOut(r *http.Request) bool {
+	return errors.Is(r.Context().Err(), context.DeadlineExceeded)
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBoundedBodyLimit(t *testing.T) {
+	handler := bounded(routeLimit{maxBody: 32}, func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		respondWithJSON(w, payload)
+	})
+
+	tests := []struct {
+		name       string
+		body       io.Reader
+		wantStatus int
+		wantCode   errorCode
+	}{
+		{name: "within limit", body: strings.NewReader(`{"a": "b"}`), wantStatus: http.StatusOK},
+		// Content-Length is rejected before the handler runs
+		{name: "declared too large", body: strings.NewReader(`{"a": "` + strings.Repeat("x", 64) + `"}`),
+			wantStatus: http.StatusRequestEntityTooLarge, wantCode: codePayloadTooLarge},
+		// A body of unknown length is cut off while decoding
+		{name: "streamed too large", body: io.MultiReader(strings.NewReader(`{"a": "` + strings.Repeat("x", 64) + `"}`)),
+			wantStatus: http.StatusRequestEntityTooLarge, wantCode: codePayloadTooLarge},
+		{name: "malformed", body: strings.NewReader(`{"a":`), wantStatus: http.StatusBadRequest, wantCode: codeInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(http.MethodPost, "/", tt.body))
+			if tt.wantCode == "" {
+				if rec.Code != tt.wantStatus {
+					t.Fatalf("expected status %d; got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+				}
+				return
+			}
+			assertErrorCode(t, rec, tt.wantStatus, tt.wantCode)
+		})
+	}
+}
+
+func TestBoundedTimeout(t *testing.T) {
+	var deadline time.Time
+	handler := bounded(routeLimit{timeout: 20 * time.Millisecond, maxBody: defaultMaxBody}, func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ = r.Context().Deadline()
+		// Stands in for an LLM or database call that gives up on the deadline
+		<-r.Context().Done()
+		writeError(w, r, codeLLMUnavailable, "The AI provider request failed. Please try again.", nil)
+	})
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assertErrorCode(t, rec, http.StatusGatewayTimeout, codeRequestTimeout)
+	if deadline.IsZero() {
+		t.Error("expected the request context to carry the deadline")
+	}
+}
+
+func TestRouteLimits(t *testing.T) {
+	s := &Server{requestTimeout: time.Second, analyzeTimeout: time.Minute}
+
+	body := `{"job_title": "` + strings.Repeat("x", analyzeMaxBody) + `"}`
+	rec := httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/analyze-job", strings.NewReader(body)))
+
+	// The body cap applies before authentication
+	assertErrorCode(t, rec, http.StatusRequestEntityTooLarge, codePayloadTooLarge)
+}
@@ -0,0 +1,577 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"upwork-buddy/internal/gemini"
+)
+
+// openAPIVersion is the version of the OpenAPI specification the document
+// follows
+const openAPIVersion = "3.0.3"
+
+// routeAuth is the credential a documented route requires
+type routeAuth int
+
+const (
+	authNone routeAuth = iota
+	authUser
+	authAdmin
+)
+
+// apiOperation documents one route. Request and response schemas are derived
+// from the Go types the handler decodes and encodes, so the document cannot
+// drift from the code.
+type apiOperation struct {
+	method  string
+	path    string
+	id      string
+	summary string
+	auth    routeAuth
+	// query documents the query parameters
+	query []queryParam
+	// headers documents request headers the handler reads
+	headers []headerParam
+	// etag marks routes whose success response carries an ETag header
+	etag bool
+	// request is a zero value of the body type, nil when there is no body
+	request interface{}
+	status  int
+	// response is a zero value of the body type, nil for empty responses
+	response interface{}
+	// others documents further statuses whose body is not an error envelope
+	others map[int]interface{}
+	// errors lists the codes the handler itself can return; codes added by
+	// the middleware are documented for every route they apply to
+	errors []errorCode
+}
+
+// queryParam documents a query parameter; schema is an OpenAPI schema
+type queryParam struct {
+	name        string
+	description string
+	schema      map[string]interface{}
+}
+
+// headerParam documents a request header
+type headerParam struct {
+	name        string
+	description string
+	required    bool
+}
+
+var (
+	stringParam  = map[string]interface{}{"type": "string"}
+	dateParam    = map[string]interface{}{"type": "string", "example": "2026-10-18"}
+	jobListQuery = []queryParam{
+		{"status", "Comma separated pipeline stages to include", stringParam},
+		{"source", "Comma separated sources to include", stringParam},
+		{"created_after", "Jobs created at or after this RFC 3339 time or YYYY-MM-DD date", dateParam},
+		{"created_before", "Jobs created before this RFC 3339 time or YYYY-MM-DD date", dateParam},
+		{"applied_after", "Jobs applied to at or after this RFC 3339 time or YYYY-MM-DD date", dateParam},
+		{"applied_before", "Jobs applied to before this RFC 3339 time or YYYY-MM-DD date", dateParam},
+		{"sort", "Sort order; prefix with - for descending", map[string]interface{}{
+			"type": "string", "default": "-created_at",
+			"enum": []string{"created_at", "-created_at", "updated_at", "-updated_at", "title", "-title"},
+		}},
+		{"limit", "Page size", map[string]interface{}{
+			"type": "integer", "minimum": 1, "maximum": maxJobPageSize, "default": defaultJobPageSize,
+		}},
+		{"cursor", "next_cursor of the previous page", stringParam},
+	}
+	revisionListQuery = []queryParam{
+		{"limit", "Page size", map[string]interface{}{
+			"type": "integer", "minimum": 1, "maximum": maxRevisionPageSize, "default": defaultRevisionPageSize,
+		}},
+		{"before", "Id of the last revision of the previous page", map[string]interface{}{"type": "integer", "minimum": 1}},
+	}
+)
+
+// apiOperations lists the documented routes. Add an entry here when
+// registering a route in RegisterRoutes; TestOpenAPIRoutesAreRegistered
+// checks that every entry is served.
+var apiOperations = []apiOperation{
+	{
+		method: http.MethodPost, path: "/api/v1/analyze-job", id: "analyzeJob",
+		summary: "Analyze a job posting and draft a proposal",
+		auth:    authUser, request: gemini.JobAnalysisRequest{},
+		headers: []headerParam{{"Client-Cache", "hit when the client already holds an analysis of the job, otherwise miss", false}},
+		status:  http.StatusOK, response: gemini.JobAnalysisResponse{},
+		errors: []errorCode{codeInvalidRequest, codeValidationFailed, codeRateLimited, codeQuotaExceeded,
+			codeLLMNotConfigured, codeLLMUnavailable, codeLLMBlocked, codeLLMRecitation, codeLLMTruncated,
+			codeLLMEmpty, codeDatabaseError, codeShuttingDown},
+	},
+	{
+		method: http.MethodGet, path: "/api/v1/profile", id: "getProfile",
+		summary: "Get the profile of the authenticated user",
+		auth:    authUser, etag: true,
+		status: http.StatusOK, response: profileResponse{},
+		errors: []errorCode{codeRateLimited, codeDatabaseError},
+	},
+	{
+		method: http.MethodPut, path: "/api/v1/profile", id: "saveProfile",
+		summary: "Replace the profile of the authenticated user",
+		auth:    authUser, request: profileRequest{}, etag: true,
+		headers: []headerParam{{"If-Match", "ETag of the profile being replaced, or *", true}},
+		status:  http.StatusOK, response: profileResponse{},
+		errors: []errorCode{codeInvalidRequest, codeValidationFailed, codePreconditionNeeded, codePreconditionFailed,
+			codeRateLimited, codeDatabaseError},
+	},
+	{
+		method: http.MethodPost, path: "/api/v1/profile", id: "saveProfileLegacy",
+		summary: "Replace the profile of the authenticated user (same as PUT, but If-Match is optional)",
+		auth:    authUser, request: profileRequest{}, etag: true,
+		headers: []headerParam{{"If-Match", "ETag of the profile being replaced, or *", false}},
+		status:  http.StatusOK, response: profileResponse{},
+		errors: []errorCode{codeInvalidRequest, codeValidationFailed, codePreconditionFailed, codeRateLimited, codeDatabaseError},
+	},
+	{
+		method: http.MethodPost, path: "/api/v1/profile/portfolio-items", id: "createPortfolioItem",
+		summary: "Add a portfolio item at the end of the portfolio",
+		auth:    authUser, request: portfolioItemRequest{},
+		status: http.StatusCreated, response: portfolioItemResponse{},
+		errors: []errorCode{codeInvalidRequest, codeValidationFailed, codeRateLimited, codeDatabaseError},
+	},
+	{
+		method: http.MethodPut, path: "/api/v1/profile/portfolio-items/order", id: "reorderPortfolioItems",
+		summary: "Reorder the portfolio items, listing every item once",
+		auth:    authUser, request: portfolioOrderRequest{},
+		status: http.StatusOK, response: []portfolioItemResponse{},
+		errors: []errorCode{codeInvalidRequest, codeValidationFailed, codeRateLimited, codeDatabaseError},
+	},
+	{
+		method: http.MethodPatch, path: "/api/v1/profile/portfolio-items/{id}", id: "updatePortfolioItem",
+		summary: "Change the fields of a portfolio item that are present in the body",
+		auth:    authUser, request: portfolioItemPatch{},
+		status: http.StatusOK, response: portfolioItemResponse{},
+		errors: []errorCode{codeInvalidRequest, codeValidationFailed, codeNotFound, codeRateLimited, codeDatabaseError},
+	},
+	{
+		method: http.MethodDelete, path: "/api/v1/profile/portfolio-items/{id}", id: "deletePortfolioItem",
+		summary: "Remove a portfolio item",
+		auth:    authUser,
+		status:  http.StatusNoContent,
+		errors:  []errorCode{codeNotFound, codeRateLimited, codeDatabaseError},
+	},
+	{
+		method: http.MethodGet, path: "/api/v1/profile/revisions", id: "listProfileRevisions",
+		summary: "List the saved revisions of the profile, newest first",
+		auth:    authUser, query: revisionListQuery,
+		status: http.StatusOK, response: []profileRevisionSummary{},
+		errors: []errorCode{codeValidationFailed, codeRateLimited, codeDatabaseError},
+	},
+	{
+		method: http.MethodGet, path: "/api/v1/profile/revisions/{id}", id: "getProfileRevision",
+		summary: "Get a profile revision with its portfolio items",
+		auth:    authUser,
+		status:  http.StatusOK, response: profileRevisionResponse{},
+		errors: []errorCode{codeNotFound, codeRateLimited, codeDatabaseError},
+	},
+	{
+		method: http.MethodGet, path: "/api/v1/profile/revisions/{id}/diff", id: "diffProfileRevisions",
+		summary: "List the changes from another revision, by default the previous one, to this one",
+		auth:    authUser, query: []queryParam{{"against", "Revision to compare with", map[string]interface{}{"type": "integer", "minimum": 1}}},
+		status: http.StatusOK, response: profileDiffResponse{},
+		errors: []errorCode{codeNotFound, codeRateLimited, codeDatabaseError},
+	},
+	{
+		method: http.MethodPost, path: "/api/v1/profile/revisions/{id}/restore", id: "restoreProfileRevision",
+		summary: "Save the content of a revision as the current profile",
+		auth:    authUser, etag: true,
+		headers: []headerParam{{"If-Match", "ETag of the profile being replaced, or *", false}},
+		status:  http.StatusOK, response: profileResponse{},
+		errors: []errorCode{codeNotFound, codePreconditionFailed, codeRateLimited, codeDatabaseError},
+	},
+	{
+		method: http.MethodGet, path: "/api/v1/jobs", id: "listJobs",
+		summary: "List tracked jobs of the authenticated user",
+		auth:    authUser, query: jobListQuery,
+		status: http.StatusOK, response: jobListResponse{},
+		errors: []errorCode{codeValidationFailed, codeRateLimited, codeDatabaseError},
+	},
+	{
+		method: http.MethodPost, path: "/api/v1/jobs", id: "createJob",
+		summary: "Start tracking a job",
+		auth:    authUser, request: jobRequest{},
+		status: http.StatusCreated, response: jobResponse{},
+		errors: []errorCode{codeInvalidRequest, codeValidationFailed, codeRateLimited, codeDatabaseError},
+	},
+	{
+		method: http.MethodGet, path: "/api/v1/jobs/{id}", id: "getJob",
+		summary: "Get a tracked job",
+		auth:    authUser,
+		status:  http.StatusOK, response: jobResponse{},
+		errors: []errorCode{codeNotFound, codeRateLimited, codeDatabaseError},
+	},
+	{
+		method: http.MethodPatch, path: "/api/v1/jobs/{id}", id: "updateJob",
+		summary: "Change the fields of a tracked job that are present in the body",
+		auth:    authUser, request: jobRequest{},
+		status: http.StatusOK, response: jobResponse{},
+		errors: []errorCode{codeInvalidRequest, codeValidationFailed, codeNotFound, codeInvalidTransition, codeRateLimited, codeDatabaseError},
+	},
+	{
+		method: http.MethodDelete, path: "/api/v1/jobs/{id}", id: "deleteJob",
+		summary: "Stop tracking a job",
+		auth:    authUser,
+		status:  http.StatusNoContent,
+		errors:  []errorCode{codeNotFound, codeRateLimited, codeDatabaseError},
+	},
+	{
+		method: http.MethodGet, path: "/api/v1/jobs/{id}/transitions", id: "listJobTransitions",
+		summary: "List the pipeline stages a job went through, oldest first",
+		auth:    authUser,
+		status:  http.StatusOK, response: []jobTransitionResponse{},
+		errors: []errorCode{codeNotFound, codeRateLimited, codeDatabaseError},
+	},
+	{
+		method: http.MethodPost, path: "/api/v1/jobs/{id}/transitions", id: "transitionJob",
+		summary: "Move a job to another pipeline stage",
+		auth:    authUser, request: jobTransitionRequest{},
+		status: http.StatusOK, response: jobResponse{},
+		errors: []errorCode{codeInvalidRequest, codeValidationFailed, codeNotFound, codeInvalidTransition, codeRateLimited, codeDatabaseError},
+	},
+	{
+		method: http.MethodPost, path: "/api/v1/admin/users", id: "createUser",
+		summary: "Create a user and issue their API key",
+		auth:    authAdmin, request: createUserRequest{},
+		status: http.StatusCreated, response: apiKeyResponse{},
+		errors: []errorCode{codeInvalidRequest, codeValidationFailed, codeConflict, codeRateLimited, codeDatabaseError, codeInternal},
+	},
+	{
+		method: http.MethodGet, path: "/api/v1/admin/users", id: "listUsers",
+		summary: "List users",
+		auth:    authAdmin,
+		status:  http.StatusOK, response: []userResponse{},
+		errors: []errorCode{codeRateLimited, codeDatabaseError},
+	},
+	{
+		method: http.MethodPost, path: "/api/v1/admin/users/{id}/api-key", id: "issueAPIKey",
+		summary: "Issue a new API key, replacing the current one",
+		auth:    authAdmin,
+		status:  http.StatusCreated, response: apiKeyResponse{},
+		errors: []errorCode{codeNotFound, codeRateLimited, codeDatabaseError, codeInternal},
+	},
+	{
+		method: http.MethodDelete, path: "/api/v1/admin/users/{id}/api-key", id: "revokeAPIKey",
+		summary: "Revoke the API key of a user",
+		auth:    authAdmin,
+		status:  http.StatusNoContent,
+		errors:  []errorCode{codeNotFound, codeRateLimited, codeDatabaseError},
+	},
+	{
+		method: http.MethodGet, path: "/api/openapi.json", id: "getOpenAPI",
+		summary: "This document",
+		status:  http.StatusOK, response: map[string]interface{}{},
+	},
+	{
+		method: http.MethodGet, path: "/livez", id: "livez",
+		summary: "Liveness probe; answers while the process serves requests",
+		status:  http.StatusOK, response: livenessResponse{},
+	},
+	{
+		method: http.MethodGet, path: "/readyz", id: "readyz",
+		summary: "Readiness probe with the status of the database, migrations and LLM provider",
+		status:  http.StatusOK, response: readinessResponse{},
+		others: map[int]interface{}{http.StatusServiceUnavailable: readinessResponse{}},
+	},
+	{
+		method: http.MethodGet, path: "/health", id: "health",
+		summary: "Readiness probe under its former name, same as /readyz",
+		status:  http.StatusOK, response: readinessResponse{},
+		others: map[int]interface{}{http.StatusServiceUnavailable: readinessResponse{}},
+	},
+}
+
+// openAPIDocument is built once on first use
+var openAPIDocument = sync.OnceValue(func() []byte {
+	doc, err := json.Marshal(buildOpenAPI(apiOperations))
+	if err != nil {
+		// Only maps, slices and strings are marshalled
+		panic(err)
+	}
+	return doc
+})
+
+// openAPIHandler serves the OpenAPI document at GET /api/openapi.json
+func (s *Server) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(openAPIDocument()); err != nil {
+		slog.ErrorContext(r.Context(), "failed to write response", "error", err)
+	}
+}
+
+// buildOpenAPI returns the OpenAPI document describing ops
+func buildOpenAPI(ops []apiOperation) map[string]interface{} {
+	schemas := &schemaBuilder{components: map[string]interface{}{}}
+	errorRef := schemas.schema(reflect.TypeOf(errorBody{}))
+	// Request fields are checked by the validators in validation.go, which
+	// report every violation as validation_failed, so none are required by
+	// the schema
+	requests := &schemaBuilder{components: schemas.components, optional: true}
+
+	paths := map[string]interface{}{}
+	for _, op := range ops {
+		item, _ := paths[op.path].(map[string]interface{})
+		if item == nil {
+			item = map[string]interface{}{}
+			paths[op.path] = item
+		}
+		item[strings.ToLower(op.method)] = op.document(schemas, requests, errorRef)
+	}
+
+	return map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info": map[string]interface{}{
+			"title":   "Upwork Buddy API",
+			"version": supportedAPIVersions[len(supportedAPIVersions)-1],
+			"description": "Every /api/v1 route is also served without the version segment as a deprecated alias. " +
+				"Error codes are described in API_ERRORS.md.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer"},
+				"apiKeyHeader": map[string]interface{}{
+					"type": "apiKey", "in": "header", "name": "X-API-Key",
+				},
+			},
+		},
+	}
+}
+
+func (op apiOperation) document(schemas, requests *schemaBuilder, errorRef map[string]interface{}) map[string]interface{} {
+	doc := map[string]interface{}{
+		"operationId": op.id,
+		"summary":     op.summary,
+	}
+
+	var params []interface{}
+	for _, segment := range strings.Split(op.path, "/") {
+		if name, ok := strings.CutPrefix(segment, "{"); ok {
+			params = append(params, map[string]interface{}{
+				"name": strings.TrimSuffix(name, "}"), "in": "path", "required": true,
+				"schema": map[string]interface{}{"type": "integer", "minimum": 1},
+			})
+		}
+	}
+	for _, q := range op.query {
+		params = append(params, map[string]interface{}{
+			"name": q.name, "in": "query", "description": q.description, "schema": q.schema,
+		})
+	}
+	for _, h := range op.headers {
+		params = append(params, map[string]interface{}{
+			"name": h.name, "in": "header", "description": h.description, "required": h.required, "schema": stringParam,
+		})
+	}
+	if params != nil {
+		doc["parameters"] = params
+	}
+
+	if op.request != nil {
+		doc["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  jsonContent(requests.schema(reflect.TypeOf(op.request))),
+		}
+	}
+
+	success := map[string]interface{}{"description": http.StatusText(op.status)}
+	if op.etag {
+		success["headers"] = map[string]interface{}{
+			"ETag": map[string]interface{}{"description": "Version of the resource, for If-Match", "schema": stringParam},
+		}
+	}
+	if op.response != nil {
+		success["content"] = jsonContent(schemas.schema(reflect.TypeOf(op.response)))
+	}
+	responses := map[string]interface{}{strconv.Itoa(op.status): success}
+	for status, body := range op.others {
+		responses[strconv.Itoa(status)] = map[string]interface{}{
+			"description": http.StatusText(status),
+			"content":     jsonContent(schemas.schema(reflect.TypeOf(body))),
+		}
+	}
+
+	codes := slices.Clone(op.errors)
+	if op.auth != authNone {
+		doc["security"] = []interface{}{
+			map[string]interface{}{"bearerAuth": []string{}},
+			map[string]interface{}{"apiKeyHeader": []string{}},
+		}
+		codes = append(codes, codeUnauthorized, codeForbidden)
+	}
+	if op.auth == authAdmin {
+		doc["description"] = "Requires the admin key or the API key of an admin user."
+	}
+	if strings.HasPrefix(op.path, "/api/") {
+		codes = append(codes, codeUnsupportedVersion)
+	}
+	// Every route caps its body, and any server-side failure past the
+	// route deadline is reported as a timeout
+	codes = append(codes, codePayloadTooLarge)
+	if slices.ContainsFunc(codes, func(code errorCode) bool { return errorCatalogue[code].status >= http.StatusInternalServerError }) {
+		codes = append(codes, codeRequestTimeout)
+	}
+	for status, statusCodes := range groupByStatus(codes) {
+		responses[strconv.Itoa(status)] = map[string]interface{}{
+			"description": "Error codes: " + strings.Join(statusCodes, ", "),
+			"content":     jsonContent(errorRef),
+		}
+	}
+	doc["responses"] = responses
+	return doc
+}
+
+// groupByStatus maps each HTTP status to the sorted error codes sent with it
+func groupByStatus(codes []errorCode) map[int][]string {
+	grouped := map[int][]string{}
+	for _, code := range codes {
+		status := errorCatalogue[code].status
+		if !slices.Contains(grouped[status], string(code)) {
+			grouped[status] = append(grouped[status], string(code))
+		}
+	}
+	for _, c := range grouped {
+		slices.Sort(c)
+	}
+	return grouped
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// schemaBuilder converts Go types to JSON schemas following the rules of
+// encoding/json. Named structs become components referenced with $ref.
+// Fields without omitempty are always encoded and therefore required, unless
+// optional is set.
+type schemaBuilder struct {
+	components map[string]interface{}
+	optional   bool
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	errorCodeType  = reflect.TypeOf(errorCode(""))
+)
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		// Used for model output that is either prose or a structured object
+		return map[string]interface{}{"oneOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{"type": "object"},
+		}}
+	case errorCodeType:
+		codes := make([]string, 0, len(errorCatalogue))
+		for code := range errorCatalogue {
+			codes = append(codes, string(code))
+		}
+		slices.Sort(codes)
+		return map[string]interface{}{"type": "string", "enum": codes}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(b.schema(t.Elem()))
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		schema := map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+		if t.Kind() == reflect.Slice {
+			// encoding/json writes nil slices as null
+			schema["nullable"] = true
+		}
+		return schema
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		return b.structRef(t)
+	default:
+		// interface{} holds any JSON value
+		return map[string]interface{}{}
+	}
+}
+
+// structRef registers t as a component and returns a reference to it
+func (b *schemaBuilder) structRef(t reflect.Type) map[string]interface{} {
+	name := componentName(t)
+	ref := map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	if _, ok := b.components[name]; ok {
+		return ref
+	}
+	// Register before walking the fields so recursive types terminate
+	b.components[name] = map[string]interface{}{}
+
+	properties := map[string]interface{}{}
+	required := []string{}
+	b.addFields(t, properties, &required)
+	component := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		component["required"] = required
+	}
+	b.components[name] = component
+	return ref
+}
+
+func (b *schemaBuilder) addFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			b.addFields(field.Type, properties, required)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = b.schema(field.Type)
+		if !b.optional && !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// nullable marks schema as accepting null; references are wrapped because
+// siblings of $ref are ignored
+func nullable(schema map[string]interface{}) map[string]interface{} {
+	if _, ok := schema["$ref"]; ok {
+		return map[string]interface{}{"allOf": []interface{}{schema}, "nullable": true}
+	}
+	schema["nullable"] = true
+	return schema
+}
+
+// componentName is the exported form of the Go type name, e.g. profileRequest
+// becomes ProfileRequest
+func componentName(t reflect.Type) string {
+	name := []rune(t.Name())
+	name[0] = unicode.ToUpper(name[0])
+	return string(name)
+}
@@ -0,0 +1,833 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	dbservice "upwork-buddy/internal/database/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// loadOpenAPI fetches the served document through the full middleware chain
+func loadOpenAPI(t *testing.T) map[string]interface{} {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	(&Server{}).RegisterRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status OK; got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to decode document: %v", err)
+	}
+	return doc
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	doc := loadOpenAPI(t)
+
+	if doc["openapi"] != openAPIVersion {
+		t.Errorf("expected openapi %s; got %v", openAPIVersion, doc["openapi"])
+	}
+	var refs []string
+	collectRefs(doc, &refs)
+	if len(refs) == 0 {
+		t.Fatal("expected schemas to be referenced")
+	}
+	for _, ref := range refs {
+		if resolveRef(doc, ref) == nil {
+			t.Errorf("unresolved reference %s", ref)
+		}
+	}
+}
+
+func TestOpenAPIRoutesAreRegistered(t *testing.T) {
+	mux := (&Server{}).routes()
+	for _, op := range apiOperations {
+		path := strings.ReplaceAll(op.path, "{id}", "1")
+		_, pattern := mux.Handler(httptest.NewRequest(op.method, path, nil))
+		if want := op.method + " " + op.path; pattern != want {
+			t.Errorf("expected %s to be routed to %q; got %q", op.id, want, pattern)
+		}
+	}
+}
+
+// TestOpenAPIContract checks responses written by the handlers against the
+// schemas documented for their route and status. Handlers that need the
+// database run their queries against sqlmock.
+func TestOpenAPIContract(t *testing.T) {
+	doc := loadOpenAPI(t)
+	created := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	user := &dbservice.User{ID: 7, Email: "jane@example.com", Name: "Jane"}
+	profileRow := func(version int) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "user_id", "description", "skills", "version", "created_at", "updated_at"}).
+			AddRow(3, user.ID, "Go developer", "Go, Docker", version, created, created)
+	}
+	itemRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "profile_id", "title", "link", "description", "position", "created_at", "updated_at"}).
+			AddRow(1, 3, "Tracker", "https://example.com", "", 0, created, created).
+			AddRow(2, 3, "Shop", "", "Storefront", 1, created, created)
+	}
+	revisionRow := func(id, version int) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "profile_id", "version", "description", "skills", "restored_from_id", "created_at"}).
+			AddRow(id, 3, version, "Go developer", "Go, Docker", nil, created)
+	}
+	revisionItemRows := func(revisionID int, title string) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "revision_id", "item_id", "title", "link", "description", "position"}).
+			AddRow(revisionID*10, revisionID, 1, title, "", "", 0)
+	}
+	userRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "email", "name", "is_admin", "api_key_hash", "api_key_prefix", "api_key_created_at", "created_at", "updated_at"}).
+			AddRow(8, "sam@example.com", "Sam", false, "hash", "ubk_abcdefgh", created, created, created)
+	}
+	jobRows := func(status string) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "title", "source", "status", "user_id", "created_at", "updated_at", "applied_at"}).
+			AddRow(2, "Go API", "upwork", status, user.ID, created, created, created)
+	}
+	// expectSnapshot scripts the revision bumpProfile records for profile 3
+	expectSnapshot := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`SELECT \* FROM "portfolio_items"`).WillReturnRows(itemRows())
+		mock.ExpectQuery(`INSERT INTO "profile_revisions"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+		mock.ExpectQuery(`INSERT INTO "profile_revision_items"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(90).AddRow(91))
+	}
+	// expectRevision scripts bumpProfile after a change to profile 3
+	expectRevision := func(mock sqlmock.Sqlmock) {
+		mock.ExpectExec(`UPDATE "profiles" SET "version"`).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectSnapshot(mock)
+	}
+	// expectReplace scripts bumpProfile after a whole profile is saved. The
+	// saved items are still set on the profile and are upserted with it.
+	expectReplace := func(mock sqlmock.Sqlmock) {
+		mock.ExpectExec(`UPDATE "profiles" SET "version"`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO "portfolio_items" .* ON CONFLICT`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, created).AddRow(4, created))
+		expectSnapshot(mock)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		run    func(t *testing.T) *httptest.ResponseRecorder
+	}{
+		{
+			name: "analysis", method: http.MethodPost, path: "/api/v1/analyze-job",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				body := `{"job_title": "Go developer for REST API with PostgreSQL",
+					"job_description": "Build shipment tracking endpoints backed by PostgreSQL and deployed with Docker.",
+					"skills": "Go, PostgreSQL, Docker",
+					"user_profile": "Backend engineer with 8 years of Go experience.",
+					"user_skills": "Go, PostgreSQL, Docker"}`
+				return serve(newReplayServer(t, "analyze_job.json").analyzeJobHandler, http.MethodPost, body)
+			},
+		},
+		{
+			name: "analysis validation error", method: http.MethodPost, path: "/api/v1/analyze-job",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				return serve((&Server{}).analyzeJobHandler, http.MethodPost, `{"job_title": ""}`)
+			},
+		},
+		{
+			name: "analysis without provider", method: http.MethodPost, path: "/api/v1/analyze-job",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				return serve((&Server{}).analyzeJobHandler, http.MethodPost, `{"job_title": "Go API", "job_description": "Build an API"}`)
+			},
+		},
+		{
+			name: "analysis without key", method: http.MethodPost, path: "/api/v1/analyze-job",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				return route(http.MethodPost, "/api/v1/analyze-job", "{}")
+			},
+		},
+		{
+			name: "profile validation error", method: http.MethodPut, path: "/api/v1/profile",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				rec := httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"portfolio_items": [{"link": "ftp://example.com"}]}`))
+				req.Header.Set("If-Match", `"1"`)
+				(&Server{}).saveProfile(rec, req)
+				return rec
+			},
+		},
+		{
+			name: "profile without If-Match", method: http.MethodPut, path: "/api/v1/profile",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				return serve((&Server{}).saveProfile, http.MethodPut, `{"description": "Go developer"}`)
+			},
+		},
+		{
+			// Item 1 is kept, item 2 removed and a new one added
+			name: "saved profile", method: http.MethodPut, path: "/api/v1/profile",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT \* FROM "profiles" .* FOR UPDATE`).WillReturnRows(profileRow(2))
+				mock.ExpectQuery(`SELECT \* FROM "portfolio_items"`).WillReturnRows(itemRows())
+				mock.ExpectExec(`UPDATE "profiles"`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`DELETE FROM "portfolio_items"`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`UPDATE "portfolio_items"`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(`INSERT INTO "portfolio_items"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+				expectReplace(mock)
+				mock.ExpectCommit()
+				req := userRequest(http.MethodPut, "/", `{"description": "Go developer", "skills": "Go",
+					"portfolio_items": [{"id": 1, "title": "Tracker"}, {"title": "CLI"}]}`, user)
+				req.Header.Set("If-Match", `"2"`)
+				rec := serveRequest(s.saveProfile, req)
+				if etag := rec.Header().Get("ETag"); etag != `"3"` {
+					t.Errorf("expected ETag of version 3; got %q", etag)
+				}
+				return rec
+			},
+		},
+		{
+			// The POST alias of older clients saves without If-Match
+			name: "saved profile without If-Match", method: http.MethodPost, path: "/api/v1/profile",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT \* FROM "profiles" .* FOR UPDATE`).WillReturnRows(profileRow(2))
+				mock.ExpectQuery(`SELECT \* FROM "portfolio_items"`).WillReturnRows(itemRows())
+				mock.ExpectExec(`UPDATE "profiles"`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`UPDATE "portfolio_items"`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`UPDATE "portfolio_items"`).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectReplace(mock)
+				mock.ExpectCommit()
+				return serveRequest(s.saveProfile, userRequest(http.MethodPost, "/", `{"description": "Go developer",
+					"portfolio_items": [{"id": 2, "title": "Shop"}, {"id": 1, "title": "Tracker"}]}`, user))
+			},
+		},
+		{
+			name: "stale profile", method: http.MethodPut, path: "/api/v1/profile",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT \* FROM "profiles" .* FOR UPDATE`).WillReturnRows(profileRow(4))
+				mock.ExpectQuery(`SELECT \* FROM "portfolio_items"`).WillReturnRows(itemRows())
+				mock.ExpectRollback()
+				req := userRequest(http.MethodPut, "/", `{"description": "Go developer"}`, user)
+				req.Header.Set("If-Match", `"3"`)
+				return serveRequest(s.saveProfile, req)
+			},
+		},
+		{
+			name: "stored profile", method: http.MethodGet, path: "/api/v1/profile",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectQuery(`SELECT \* FROM "profiles"`).WillReturnRows(profileRow(2))
+				mock.ExpectQuery(`SELECT \* FROM "portfolio_items"`).WillReturnRows(itemRows())
+				return serveRequest(s.getProfile, userRequest(http.MethodGet, "/", "", user))
+			},
+		},
+		{
+			// getProfile answers with an empty profile when none is stored
+			name: "missing profile", method: http.MethodGet, path: "/api/v1/profile",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectQuery(`SELECT \* FROM "profiles"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				return serveRequest(s.getProfile, userRequest(http.MethodGet, "/", "", user))
+			},
+		},
+		{
+			name: "created portfolio item", method: http.MethodPost, path: "/api/v1/profile/portfolio-items",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT \* FROM "profiles" .* FOR UPDATE`).WillReturnRows(profileRow(2))
+				mock.ExpectQuery(`SELECT COUNT\(\*\) AS count`).WillReturnRows(sqlmock.NewRows([]string{"count", "next"}).AddRow(2, 2))
+				mock.ExpectQuery(`INSERT INTO "portfolio_items"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+				expectRevision(mock)
+				mock.ExpectCommit()
+				return serveRequest(s.createPortfolioItem, userRequest(http.MethodPost, "/", `{"title": "CLI", "link": "https://example.com/cli"}`, user))
+			},
+		},
+		{
+			name: "invalid portfolio item", method: http.MethodPost, path: "/api/v1/profile/portfolio-items",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				return serve((&Server{}).createPortfolioItem, http.MethodPost, `{"title": "", "link": "not a url"}`)
+			},
+		},
+		{
+			// Only the patched field is written, under the profile lock
+			name: "updated portfolio item", method: http.MethodPatch, path: "/api/v1/profile/portfolio-items/{id}",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT \* FROM "profiles" .* FOR UPDATE`).WillReturnRows(profileRow(2))
+				mock.ExpectQuery(`SELECT \* FROM "portfolio_items" WHERE profile_id = \$1 AND "portfolio_items"."id" = \$2`).
+					WillReturnRows(itemRows())
+				mock.ExpectExec(`UPDATE "portfolio_items" SET "title"=\$1,"updated_at"=\$2 WHERE "id" = \$3`).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectRevision(mock)
+				mock.ExpectCommit()
+				req := userRequest(http.MethodPatch, "/", `{"title": "Tracker v2"}`, user)
+				req.SetPathValue("id", "1")
+				return serveRequest(s.updatePortfolioItem, req)
+			},
+		},
+		{
+			// The item went away before the update
+			name: "updated portfolio item gone", method: http.MethodPatch, path: "/api/v1/profile/portfolio-items/{id}",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT \* FROM "profiles" .* FOR UPDATE`).WillReturnRows(profileRow(2))
+				mock.ExpectQuery(`SELECT \* FROM "portfolio_items"`).WillReturnRows(itemRows())
+				mock.ExpectExec(`UPDATE "portfolio_items"`).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+				req := userRequest(http.MethodPatch, "/", `{"link": "https://example.com/v2"}`, user)
+				req.SetPathValue("id", "1")
+				rec := serveRequest(s.updatePortfolioItem, req)
+				assertErrorCode(t, rec, http.StatusNotFound, codeNotFound)
+				return rec
+			},
+		},
+		{
+			name: "deleted portfolio item", method: http.MethodDelete, path: "/api/v1/profile/portfolio-items/{id}",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectQuery(`SELECT \* FROM "portfolio_items" WHERE profile_id IN`).WillReturnRows(itemRows())
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT \* FROM "profiles" .* FOR UPDATE`).WillReturnRows(profileRow(2))
+				mock.ExpectExec(`DELETE FROM "portfolio_items"`).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectRevision(mock)
+				mock.ExpectCommit()
+				req := userRequest(http.MethodDelete, "/", "", user)
+				req.SetPathValue("id", "1")
+				return serveRequest(s.deletePortfolioItem, req)
+			},
+		},
+		{
+			name: "reordered portfolio", method: http.MethodPut, path: "/api/v1/profile/portfolio-items/order",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT \* FROM "profiles" .* FOR UPDATE`).WillReturnRows(profileRow(2))
+				mock.ExpectQuery(`SELECT \* FROM "portfolio_items"`).WillReturnRows(itemRows())
+				mock.ExpectExec(`UPDATE "portfolio_items" SET "position"`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`UPDATE "portfolio_items" SET "position"`).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectRevision(mock)
+				mock.ExpectCommit()
+				return serveRequest(s.reorderPortfolioItems, userRequest(http.MethodPut, "/", `{"ids": [2, 1]}`, user))
+			},
+		},
+		{
+			name: "profile revisions", method: http.MethodGet, path: "/api/v1/profile/revisions",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectQuery(`FROM "profile_revisions"`).WillReturnRows(
+					sqlmock.NewRows([]string{"id", "version", "restored_from", "created_at", "portfolio_items", "analyses"}).
+						AddRow(3, 3, 1, created, 2, 4).
+						AddRow(2, 2, nil, created, 1, 0))
+				return serveRequest(s.listProfileRevisions, userRequest(http.MethodGet, "/", "", user))
+			},
+		},
+		{
+			name: "profile revision", method: http.MethodGet, path: "/api/v1/profile/revisions/{id}",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectQuery(`SELECT \* FROM "profile_revisions"`).WillReturnRows(revisionRow(2, 2))
+				mock.ExpectQuery(`SELECT \* FROM "profile_revision_items"`).WillReturnRows(revisionItemRows(2, "Tracker"))
+				req := userRequest(http.MethodGet, "/", "", user)
+				req.SetPathValue("id", "2")
+				return serveRequest(s.getProfileRevision, req)
+			},
+		},
+		{
+			name: "profile diff", method: http.MethodGet, path: "/api/v1/profile/revisions/{id}/diff",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectQuery(`SELECT \* FROM "profile_revisions"`).WillReturnRows(revisionRow(2, 2))
+				mock.ExpectQuery(`SELECT \* FROM "profile_revision_items"`).WillReturnRows(revisionItemRows(2, "Tracker v2"))
+				mock.ExpectQuery(`SELECT \* FROM "profile_revisions" WHERE profile_id = .* AND version <`).WillReturnRows(revisionRow(1, 1))
+				mock.ExpectQuery(`SELECT \* FROM "profile_revision_items"`).WillReturnRows(revisionItemRows(1, "Tracker"))
+				req := userRequest(http.MethodGet, "/", "", user)
+				req.SetPathValue("id", "2")
+				return serveRequest(s.diffProfileRevisions, req)
+			},
+		},
+		{
+			// Revision 2 keeps item 1 only, so item 2 is removed
+			name: "restored revision", method: http.MethodPost, path: "/api/v1/profile/revisions/{id}/restore",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectQuery(`SELECT \* FROM "profile_revisions"`).WillReturnRows(revisionRow(2, 2))
+				mock.ExpectQuery(`SELECT \* FROM "profile_revision_items"`).WillReturnRows(revisionItemRows(2, "Tracker"))
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT \* FROM "profiles" .* FOR UPDATE`).WillReturnRows(profileRow(4))
+				mock.ExpectQuery(`SELECT \* FROM "portfolio_items"`).WillReturnRows(itemRows())
+				mock.ExpectExec(`UPDATE "profiles"`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`DELETE FROM "portfolio_items"`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`UPDATE "portfolio_items"`).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectReplace(mock)
+				mock.ExpectCommit()
+				req := userRequest(http.MethodPost, "/", "", user)
+				req.SetPathValue("id", "2")
+				req.Header.Set("If-Match", `"4"`)
+				return serveRequest(s.restoreProfileRevision, req)
+			},
+		},
+		{
+			name: "stale restore", method: http.MethodPost, path: "/api/v1/profile/revisions/{id}/restore",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectQuery(`SELECT \* FROM "profile_revisions"`).WillReturnRows(revisionRow(2, 2))
+				mock.ExpectQuery(`SELECT \* FROM "profile_revision_items"`).WillReturnRows(revisionItemRows(2, "Tracker"))
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT \* FROM "profiles" .* FOR UPDATE`).WillReturnRows(profileRow(5))
+				mock.ExpectQuery(`SELECT \* FROM "portfolio_items"`).WillReturnRows(itemRows())
+				mock.ExpectRollback()
+				req := userRequest(http.MethodPost, "/", "", user)
+				req.SetPathValue("id", "2")
+				req.Header.Set("If-Match", `"4"`)
+				return serveRequest(s.restoreProfileRevision, req)
+			},
+		},
+		{
+			name: "job list", method: http.MethodGet, path: "/api/v1/jobs",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectQuery(`SELECT \* FROM "jobs"`).WillReturnRows(jobRows(stageApplied).
+					AddRow(1, "React app", "upwork", stageArchived, user.ID, created, created, nil))
+				return serveRequest(s.listJobs, userRequest(http.MethodGet, "/api/v1/jobs?limit=1", "", user))
+			},
+		},
+		{
+			name: "invalid job query", method: http.MethodGet, path: "/api/v1/jobs",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				rec := httptest.NewRecorder()
+				(&Server{}).listJobs(rec, httptest.NewRequest(http.MethodGet, "/api/v1/jobs?limit=0&sort=budget", nil))
+				return rec
+			},
+		},
+		{
+			name: "created job", method: http.MethodPost, path: "/api/v1/jobs",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`INSERT INTO "jobs"`).WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, created))
+				mock.ExpectQuery(`INSERT INTO "job_transitions"`).WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(5, created))
+				mock.ExpectCommit()
+				return serveRequest(s.createJob, userRequest(http.MethodPost, "/", `{"title": "Go API", "url": "https://www.upwork.com/jobs/1"}`, user))
+			},
+		},
+		{
+			name: "job", method: http.MethodGet, path: "/api/v1/jobs/{id}",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectQuery(`SELECT \* FROM "jobs"`).WillReturnRows(jobRows(stageApplied))
+				req := userRequest(http.MethodGet, "/", "", user)
+				req.SetPathValue("id", "2")
+				return serveRequest(s.getJob, req)
+			},
+		},
+		{
+			name: "updated job", method: http.MethodPatch, path: "/api/v1/jobs/{id}",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectQuery(`SELECT \* FROM "jobs"`).WillReturnRows(jobRows(stageApplied))
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT \* FROM "jobs" .* FOR UPDATE`).WillReturnRows(jobRows(stageApplied))
+				mock.ExpectExec(`UPDATE "jobs"`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				req := userRequest(http.MethodPatch, "/", `{"title": "Go API v2"}`, user)
+				req.SetPathValue("id", "2")
+				return serveRequest(s.updateJob, req)
+			},
+		},
+		{
+			name: "moved job", method: http.MethodPost, path: "/api/v1/jobs/{id}/transitions",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectQuery(`SELECT \* FROM "jobs"`).WillReturnRows(jobRows(stageApplied))
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT \* FROM "jobs" .* FOR UPDATE`).WillReturnRows(jobRows(stageApplied))
+				mock.ExpectExec(`UPDATE "jobs"`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(`INSERT INTO "job_transitions"`).WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(6, created))
+				mock.ExpectCommit()
+				req := userRequest(http.MethodPost, "/", `{"status": "interviewing", "note": "call on Monday"}`, user)
+				req.SetPathValue("id", "2")
+				return serveRequest(s.transitionJob, req)
+			},
+		},
+		{
+			name: "deleted job", method: http.MethodDelete, path: "/api/v1/jobs/{id}",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectQuery(`SELECT \* FROM "jobs"`).WillReturnRows(jobRows(stageApplied))
+				mock.ExpectBegin()
+				mock.ExpectExec(`DELETE FROM "jobs"`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				req := userRequest(http.MethodDelete, "/", "", user)
+				req.SetPathValue("id", "2")
+				return serveRequest(s.deleteJob, req)
+			},
+		},
+		{
+			name: "invalid job", method: http.MethodPatch, path: "/api/v1/jobs/{id}",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				return serve((&Server{}).updateJob, http.MethodPatch, `{"title": "", "url": "not a url"}`)
+			},
+		},
+		{
+			name: "invalid transition", method: http.MethodPost, path: "/api/v1/jobs/{id}/transitions",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectQuery(`SELECT \* FROM "jobs"`).WillReturnRows(jobRows(stageHired))
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT \* FROM "jobs" .* FOR UPDATE`).WillReturnRows(jobRows(stageHired))
+				mock.ExpectRollback()
+				req := userRequest(http.MethodPost, "/", `{"status": "applied"}`, user)
+				req.SetPathValue("id", "2")
+				return serveRequest(s.transitionJob, req)
+			},
+		},
+		{
+			name: "job history", method: http.MethodGet, path: "/api/v1/jobs/{id}/transitions",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectQuery(`SELECT \* FROM "jobs"`).WillReturnRows(jobRows(stageApplied))
+				mock.ExpectQuery(`SELECT \* FROM "job_transitions"`).WillReturnRows(
+					sqlmock.NewRows([]string{"id", "job_id", "from_status", "to_status", "note", "created_at"}).
+						AddRow(1, 2, nil, stageDiscovered, "", created).
+						AddRow(2, 2, stageDiscovered, stageApplied, "sent proposal", created))
+				req := userRequest(http.MethodGet, "/", "", user)
+				req.SetPathValue("id", "2")
+				return serveRequest(s.listJobTransitions, req)
+			},
+		},
+		{
+			name: "created user", method: http.MethodPost, path: "/api/v1/admin/users",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`INSERT INTO "users"`).WillReturnRows(sqlmock.NewRows([]string{"id", "is_admin", "created_at"}).AddRow(8, false, created))
+				mock.ExpectCommit()
+				return serve(s.createUser, http.MethodPost, `{"email": "sam@example.com", "name": "Sam"}`)
+			},
+		},
+		{
+			name: "user list", method: http.MethodGet, path: "/api/v1/admin/users",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectQuery(`SELECT \* FROM "users"`).WillReturnRows(
+					sqlmock.NewRows([]string{"id", "email", "name", "is_admin", "api_key_prefix", "api_key_created_at", "created_at", "updated_at"}).
+						AddRow(user.ID, user.Email, user.Name, true, "ubk_abcdefgh", created, created, created))
+				return serve(s.listUsers, http.MethodGet, "")
+			},
+		},
+		{
+			name: "issued API key", method: http.MethodPost, path: "/api/v1/admin/users/{id}/api-key",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectQuery(`SELECT \* FROM "users"`).WillReturnRows(userRows())
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE "users"`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				req := httptest.NewRequest(http.MethodPost, "/", nil)
+				req.SetPathValue("id", "8")
+				return serveRequest(s.issueAPIKey, req)
+			},
+		},
+		{
+			name: "revoked API key", method: http.MethodDelete, path: "/api/v1/admin/users/{id}/api-key",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				s, mock := newMockServer(t)
+				mock.ExpectQuery(`SELECT \* FROM "users"`).WillReturnRows(userRows())
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE "users"`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				req := httptest.NewRequest(http.MethodDelete, "/", nil)
+				req.SetPathValue("id", "8")
+				return serveRequest(s.revokeAPIKey, req)
+			},
+		},
+		{
+			name: "user list without key", method: http.MethodGet, path: "/api/v1/admin/users",
+			run: func(t *testing.T) *httptest.ResponseRecorder { return route(http.MethodGet, "/api/v1/admin/users", "") },
+		},
+		{
+			name: "invalid user", method: http.MethodPost, path: "/api/v1/admin/users",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				return serve((&Server{}).createUser, http.MethodPost, `{"email": "nope"}`)
+			},
+		},
+		{
+			name: "document", method: http.MethodGet, path: "/api/openapi.json",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				return route(http.MethodGet, "/api/openapi.json", "")
+			},
+		},
+		{
+			name: "liveness", method: http.MethodGet, path: "/livez",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				return route(http.MethodGet, "/livez", "")
+			},
+		},
+		{
+			name: "readiness", method: http.MethodGet, path: "/readyz",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				return serve((&Server{db: &fakeDB{up: true}}).readyzHandler, http.MethodGet, "")
+			},
+		},
+		{
+			name: "health", method: http.MethodGet, path: "/health",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				return serve((&Server{db: &fakeDB{up: true}}).readyzHandler, http.MethodGet, "")
+			},
+		},
+		{
+			name: "unsupported version", method: http.MethodGet, path: "/api/openapi.json",
+			run: func(t *testing.T) *httptest.ResponseRecorder {
+				rec := httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil)
+				req.Header.Set("API-Version", "9")
+				(&Server{}).RegisterRoutes().ServeHTTP(rec, req)
+				return rec
+			},
+		},
+	}
+
+	// covered collects the method, path and status of every case
+	covered := map[string]bool{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tt.run(t)
+			covered[fmt.Sprintf("%s %s %d", tt.method, tt.path, rec.Code)] = true
+			// A documented database error would pass; it means the mock
+			// was not scripted for the queries the handler ran
+			if strings.Contains(rec.Body.String(), `"code":"database_error"`) {
+				t.Fatalf("unexpected database error: %s", rec.Body.String())
+			}
+
+			if rec.Code == http.StatusNoContent {
+				if lookup(doc, "paths", tt.path, strings.ToLower(tt.method), "responses", "204") == nil {
+					t.Fatalf("status 204 is not documented for %s %s", tt.method, tt.path)
+				}
+				if rec.Body.Len() > 0 {
+					t.Errorf("expected no body; got %s", rec.Body.String())
+				}
+				return
+			}
+			schema := responseSchema(doc, tt.method, tt.path, rec.Code)
+			if schema == nil {
+				t.Fatalf("status %d is not documented for %s %s: %s", rec.Code, tt.method, tt.path, rec.Body.String())
+			}
+			var body interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			for _, violation := range validateSchema(doc, schema, body, "$") {
+				t.Error(violation)
+			}
+		})
+	}
+
+	// Every operation needs a case for its success response
+	for _, op := range apiOperations {
+		if !covered[fmt.Sprintf("%s %s %d", op.method, op.path, op.status)] {
+			t.Errorf("%s has no contract case answering %d", op.id, op.status)
+		}
+	}
+}
+
+func serve(handler http.HandlerFunc, method, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(method, "/", strings.NewReader(body)))
+	return rec
+}
+
+// serveRequest runs handler on req, which userRequest may have prepared
+func serveRequest(handler http.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+// userRequest returns a request authenticated as user
+func userRequest(method, target, body string, user *dbservice.User) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	return req.WithContext(context.WithValue(req.Context(), userKey, user))
+}
+
+// mockDB serves a GORM instance backed by sqlmock
+type mockDB struct {
+	fakeDB
+	gorm *gorm.DB
+}
+
+func (m *mockDB) GetGorm() *gorm.DB { return m.gorm }
+
+// newMockServer returns a Server whose database queries are answered by the
+// returned sqlmock. The test fails if an expected query is not run.
+func newMockServer(t *testing.T) (*Server, sqlmock.Sqlmock) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		sqlDB.Close()
+	})
+	return &Server{db: &mockDB{gorm: db}}, mock
+}
+
+func route(method, path, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	(&Server{}).RegisterRoutes().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return rec
+}
+
+// responseSchema returns the JSON schema documented for a response, or nil
+func responseSchema(doc map[string]interface{}, method, path string, status int) map[string]interface{} {
+	schema, _ := lookup(doc, "paths", path, strings.ToLower(method), "responses", strconv.Itoa(status),
+		"content", "application/json", "schema").(map[string]interface{})
+	return schema
+}
+
+func lookup(value interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+func resolveRef(doc map[string]interface{}, ref string) map[string]interface{} {
+	keys := strings.Split(strings.TrimPrefix(ref, "#/"), "/")
+	schema, _ := lookup(doc, keys...).(map[string]interface{})
+	return schema
+}
+
+func collectRefs(value interface{}, refs *[]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if ref, ok := child.(string); ok && key == "$ref" {
+				*refs = append(*refs, ref)
+			}
+			collectRefs(child, refs)
+		}
+	case []interface{}:
+		for _, child := range v {
+			collectRefs(child, refs)
+		}
+	}
+}
+
+// validateSchema checks value against the subset of OpenAPI 3.0 schemas the
+// document uses. Objects may not carry undocumented properties.
+func validateSchema(doc, schema map[string]interface{}, value interface{}, at string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		return validateSchema(doc, resolveRef(doc, ref), value, at)
+	}
+	if value == nil {
+		if schema["nullable"] == true {
+			return nil
+		}
+		return []string{at + ": null is not allowed"}
+	}
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		var violations []string
+		for _, sub := range all {
+			violations = append(violations, validateSchema(doc, sub.(map[string]interface{}), value, at)...)
+		}
+		return violations
+	}
+	if one, ok := schema["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, sub := range one {
+			if len(validateSchema(doc, sub.(map[string]interface{}), value, at)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return []string{fmt.Sprintf("%s: expected exactly one oneOf match; got %d", at, matches)}
+		}
+		return nil
+	}
+
+	var violations []string
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected object; got %T", at, value)}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				violations = append(violations, fmt.Sprintf("%s: missing required property %s", at, name))
+			}
+		}
+		additional, _ := schema["additionalProperties"].(map[string]interface{})
+		for name, child := range obj {
+			if sub, ok := properties[name].(map[string]interface{}); ok {
+				violations = append(violations, validateSchema(doc, sub, child, at+"."+name)...)
+			} else if additional != nil {
+				violations = append(violations, validateSchema(doc, additional, child, at+"."+name)...)
+			} else if properties != nil {
+				violations = append(violations, fmt.Sprintf("%s: undocumented property %s", at, name))
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected array; got %T", at, value)}
+		}
+		for i, item := range items {
+			violations = append(violations, validateSchema(doc, schema["items"].(map[string]interface{}), item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected string; got %T", at, value)}
+		}
+		if enum, ok := schema["enum"].([]interface{}); ok && !slices.Contains(enum, interface{}(s)) {
+			violations = append(violations, fmt.Sprintf("%s: %q is not one of %v", at, s, enum))
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				violations = append(violations, fmt.Sprintf("%s: %q is not a date-time", at, s))
+			}
+		}
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected %s; got %T", at, schema["type"], value)}
+		}
+		if schema["type"] == "integer" && n != float64(int64(n)) {
+			violations = append(violations, fmt.Sprintf("%s: %v is not an integer", at, n))
+		}
+		if min, ok := schema["minimum"].(float64); ok && n < min {
+			violations = append(violations, fmt.Sprintf("%s: %v is below %v", at, n, min))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s: expected boolean; got %T", at, value)}
+		}
+	}
+	return violations
+}
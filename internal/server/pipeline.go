@@ -0,0 +1,86 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	dbservice "upwork-buddy/internal/database/service"
+
+	"gorm.io/gorm"
+)
+
+// Stages of the job pipeline. A job normally moves down the list; hired,
+// declined and archived end it.
+const (
+	stageDiscovered   = "discovered"
+	stageAnalyzed     = "analyzed"
+	stageShortlisted  = "shortlisted"
+	stageApplied      = "applied"
+	stageViewed       = "viewed"
+	stageInterviewing = "interviewing"
+	stageHired        = "hired"
+	stageDeclined     = "declined"
+	stageArchived     = "archived"
+)
+
+// jobStages lists the stages in pipeline order
+var jobStages = []string{
+	stageDiscovered, stageAnalyzed, stageShortlisted, stageApplied, stageViewed,
+	stageInterviewing, stageHired, stageDeclined, stageArchived,
+}
+
+// jobTransitions lists the stages each stage may move to. Stages may be
+// skipped, for example when a proposal is sent without an analysis, but a
+// job never moves back. Any job can be archived; archived is final.
+var jobTransitions = map[string][]string{
+	stageDiscovered:   {stageAnalyzed, stageShortlisted, stageApplied, stageDeclined, stageArchived},
+	stageAnalyzed:     {stageShortlisted, stageApplied, stageDeclined, stageArchived},
+	stageShortlisted:  {stageApplied, stageDeclined, stageArchived},
+	stageApplied:      {stageViewed, stageInterviewing, stageHired, stageDeclined, stageArchived},
+	stageViewed:       {stageInterviewing, stageHired, stageDeclined, stageArchived},
+	stageInterviewing: {stageHired, stageDeclined, stageArchived},
+	stageHired:        {stageArchived},
+	stageDeclined:     {stageArchived},
+	stageArchived:     {},
+}
+
+// transitionError reports a move the pipeline does not allow
+type transitionError struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Allowed []string `json:"allowed"`
+}
+
+func (e *transitionError) Error() string {
+	return fmt.Sprintf("job cannot move from %s to %s", e.From, e.To)
+}
+
+// moveJob moves job to stage within tx and records the transition
+func moveJob(tx *gorm.DB, job *dbservice.Job, stage, note string) error {
+	from, err := advanceJob(job, stage)
+	if err != nil {
+		return err
+	}
+	if err := tx.Save(job).Error; err != nil {
+		return err
+	}
+	return tx.Create(&dbservice.JobTransition{JobID: job.ID, FromStatus: &from, ToStatus: stage, Note: note}).Error
+}
+
+// advanceJob sets the stage of job if the pipeline allows the move and
+// returns the previous stage. The first move to applied sets AppliedAt
+// unless the client already did.
+func advanceJob(job *dbservice.Job, stage string) (string, error) {
+	from := job.Status
+	if !slices.Contains(jobTransitions[from], stage) {
+		return from, &transitionError{From: from, To: stage, Allowed: jobTransitions[from]}
+	}
+
+	job.Status = stage
+	if stage == stageApplied && job.AppliedAt == nil {
+		now := time.Now()
+		job.AppliedAt = &now
+	}
+	return from, nil
+}
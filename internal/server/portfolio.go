@@ -0,0 +1,399 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	dbservice "upwork-buddy/internal/database/service"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// portfolioItemPatch is the body of PATCH
+// /api/v1/profile/portfolio-items/{id}. Only the fields that are present
+// change.
+type portfolioItemPatch struct {
+	Title       *string `json:"title"`
+	Link        *string `json:"link"`
+	Description *string `json:"description"`
+}
+
+// portfolioOrderRequest is the body of PUT
+// /api/v1/profile/portfolio-items/order. IDs lists every portfolio item of
+// the profile once, in the new order.
+type portfolioOrderRequest struct {
+	IDs []uint `json:"ids"`
+}
+
+var (
+	// errPortfolioFull stops adding an item to a portfolio at maxPortfolioItems
+	errPortfolioFull = errors.New("portfolio is full")
+	// errInvalidOrder stops a reorder that does not list every item once
+	errInvalidOrder = errors.New("invalid portfolio order")
+	// errInvalidItem stops a patch that leaves the item invalid
+	errInvalidItem = errors.New("invalid portfolio item")
+)
+
+// createPortfolioItem handles POST /api/v1/profile/portfolio-items, adding an
+// item at the end of the portfolio. A missing profile is created.
+func (s *Server) createPortfolioItem(w http.ResponseWriter, r *http.Request) {
+	var payload portfolioItemRequest
+	if !decodeJSON(w, r, &payload) {
+		return
+	}
+	if violations := validatePortfolioItem(payload); len(violations) > 0 {
+		writeError(w, r, codeValidationFailed, "The portfolio item has invalid fields", violations)
+		return
+	}
+
+	user := userFromContext(r.Context())
+	item := dbservice.PortfolioItem{Title: payload.Title, Link: payload.Link, Description: payload.Description}
+	err := s.db.GetGorm().WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+		profile, err := lockProfile(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		var stats struct {
+			Count int
+			Next  int
+		}
+		err = tx.Model(&dbservice.PortfolioItem{}).
+			Select("COUNT(*) AS count, COALESCE(MAX(position) + 1, 0) AS next").
+			Where("profile_id = ?", profile.ID).
+			Scan(&stats).Error
+		if err != nil {
+			return err
+		}
+		if stats.Count >= maxPortfolioItems {
+			return errPortfolioFull
+		}
+		item.ProfileID = profile.ID
+		item.Position = stats.Next
+		if err := tx.Create(&item).Error; err != nil {
+			return err
+		}
+		return bumpProfile(tx, profile, nil)
+	})
+	if errors.Is(err, errPortfolioFull) {
+		writeError(w, r, codeValidationFailed, "The portfolio is full", []fieldError{{
+			Field:   "portfolio_items",
+			Rule:    "max_items",
+			Message: fmt.Sprintf("portfolio_items must contain at most %d items", maxPortfolioItems),
+		}})
+		return
+	}
+	if err != nil {
+		writeError(w, r, codeDatabaseError, "Failed to save portfolio item", nil)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "portfolio item created", "item_id", item.ID, "position", item.Position)
+	respondWithStatus(w, http.StatusCreated, portfolioItemResponseFromModel(&item))
+}
+
+// updatePortfolioItem handles PATCH /api/v1/profile/portfolio-items/{id}.
+// The item is read under the profile lock and only the fields present in the
+// body are written, so concurrent patches of other fields are kept.
+func (s *Server) updatePortfolioItem(w http.ResponseWriter, r *http.Request) {
+	var payload portfolioItemPatch
+	if !decodeJSON(w, r, &payload) {
+		return
+	}
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, r, codeNotFound, "Portfolio item not found", nil)
+		return
+	}
+
+	var item dbservice.PortfolioItem
+	var violations []fieldError
+	err = s.db.GetGorm().WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+		profile, err := lockProfile(tx, userFromContext(r.Context()).ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("profile_id = ?", profile.ID).First(&item, id).Error; err != nil {
+			return err
+		}
+		payload.applyTo(&item)
+		violations = validatePortfolioItem(portfolioItemRequest{Title: item.Title, Link: item.Link, Description: item.Description})
+		if len(violations) > 0 {
+			return errInvalidItem
+		}
+		// Only the content is written, so the position set by a reorder
+		// is kept
+		result := tx.Model(&item).Select(payload.columns()).Updates(&item)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return bumpProfile(tx, profile, nil)
+	})
+	if errors.Is(err, errInvalidItem) {
+		writeError(w, r, codeValidationFailed, "The portfolio item has invalid fields", violations)
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeError(w, r, codeNotFound, "Portfolio item not found", nil)
+		return
+	}
+	if err != nil {
+		writeError(w, r, codeDatabaseError, "Failed to update portfolio item", nil)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "portfolio item updated", "item_id", item.ID)
+	respondWithJSON(w, portfolioItemResponseFromModel(&item))
+}
+
+// deletePortfolioItem handles DELETE /api/v1/profile/portfolio-items/{id}.
+// The other items keep their positions.
+func (s *Server) deletePortfolioItem(w http.ResponseWriter, r *http.Request) {
+	item, ok := s.loadPortfolioItem(w, r)
+	if !ok {
+		return
+	}
+	err := s.db.GetGorm().WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+		profile, err := lockProfile(tx, userFromContext(r.Context()).ID)
+		if err != nil {
+			return err
+		}
+		result := tx.Delete(item)
+		if result.Error != nil {
+			return result.Error
+		}
+		// Deleted by a concurrent request since it was loaded
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return bumpProfile(tx, profile, nil)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeError(w, r, codeNotFound, "Portfolio item not found", nil)
+		return
+	}
+	if err != nil {
+		writeError(w, r, codeDatabaseError, "Failed to delete portfolio item", nil)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "portfolio item deleted", "item_id", item.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reorderPortfolioItems handles PUT /api/v1/profile/portfolio-items/order,
+// returning the items in their new order
+func (s *Server) reorderPortfolioItems(w http.ResponseWriter, r *http.Request) {
+	var payload portfolioOrderRequest
+	if !decodeJSON(w, r, &payload) {
+		return
+	}
+
+	user := userFromContext(r.Context())
+	var items []dbservice.PortfolioItem
+	var violations []fieldError
+	err := s.db.GetGorm().WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+		profile, err := lockProfile(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("profile_id = ?", profile.ID).Find(&items).Error; err != nil {
+			return err
+		}
+		if violations = orderPortfolio(items, payload.IDs); len(violations) > 0 {
+			return errInvalidOrder
+		}
+		for _, item := range items {
+			if err := tx.Model(&item).Update("position", item.Position).Error; err != nil {
+				return err
+			}
+		}
+		return bumpProfile(tx, profile, nil)
+	})
+	if errors.Is(err, errInvalidOrder) {
+		writeError(w, r, codeValidationFailed, "The order must list every portfolio item once", violations)
+		return
+	}
+	if err != nil {
+		writeError(w, r, codeDatabaseError, "Failed to reorder portfolio items", nil)
+		return
+	}
+
+	resp := make([]portfolioItemResponse, 0, len(items))
+	for i := range items {
+		resp = append(resp, portfolioItemResponseFromModel(&items[i]))
+	}
+	respondWithJSON(w, resp)
+}
+
+// loadPortfolioItem fetches the portfolio item named by the {id} path
+// parameter. Items of other users are reported as not found.
+func (s *Server) loadPortfolioItem(w http.ResponseWriter, r *http.Request) (*dbservice.PortfolioItem, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, r, codeNotFound, "Portfolio item not found", nil)
+		return nil, false
+	}
+
+	db := s.db.GetGorm().WithContext(r.Context())
+	var item dbservice.PortfolioItem
+	err = ownProfileRows(db, userFromContext(r.Context()).ID).First(&item, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeError(w, r, codeNotFound, "Portfolio item not found", nil)
+			return nil, false
+		}
+		writeError(w, r, codeDatabaseError, "Failed to load portfolio item", nil)
+		return nil, false
+	}
+	return &item, true
+}
+
+// ownProfileRows scopes db to the rows whose profile_id is the profile of
+// the user, such as portfolio items and profile revisions
+func ownProfileRows(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Where("profile_id IN (?)", db.Session(&gorm.Session{NewDB: true}).
+		Model(&dbservice.Profile{}).Select("id").Where("user_id = ?", userID))
+}
+
+// lockProfile loads the profile of the user and locks its row until the
+// transaction ends, so concurrent edits of the profile and its items apply
+// one after another. A missing profile is created empty.
+func lockProfile(tx *gorm.DB, userID uint) (*dbservice.Profile, error) {
+	var profile dbservice.Profile
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&profile).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		profile = dbservice.Profile{UserID: userID}
+		err = tx.Create(&profile).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// mergePortfolio matches the items of a profile save with the stored items
+// by id. It returns the items to save, positioned in request order, and the
+// ids of the stored items the request no longer lists. Blank items are
+// dropped; an unknown or repeated id adds a new item.
+func mergePortfolio(profileID uint, stored []dbservice.PortfolioItem, reqs []portfolioItemRequest) ([]dbservice.PortfolioItem, []uint) {
+	byID := make(map[uint]dbservice.PortfolioItem, len(stored))
+	for _, item := range stored {
+		byID[item.ID] = item
+	}
+
+	items := make([]dbservice.PortfolioItem, 0, len(reqs))
+	for _, req := range reqs {
+		if blankPortfolioItem(req) {
+			continue
+		}
+		item, ok := byID[req.ID]
+		if ok {
+			delete(byID, req.ID)
+		} else {
+			item = dbservice.PortfolioItem{ProfileID: profileID}
+		}
+		item.Title = req.Title
+		item.Link = req.Link
+		item.Description = req.Description
+		item.Position = len(items)
+		items = append(items, item)
+	}
+
+	var removed []uint
+	for _, item := range stored {
+		if _, ok := byID[item.ID]; ok {
+			removed = append(removed, item.ID)
+		}
+	}
+	return items, removed
+}
+
+// orderPortfolio sets the position of each item to its index in ids and
+// sorts items by it. ids must list every item exactly once; otherwise items
+// are left as they were and the violations are returned.
+func orderPortfolio(items []dbservice.PortfolioItem, ids []uint) []fieldError {
+	var v validator
+	index := make(map[uint]int, len(ids))
+	for i, id := range ids {
+		if _, seen := index[id]; seen {
+			v.add(fmt.Sprintf("ids[%d]", i), "order", fmt.Sprintf("ids lists item %d more than once", id))
+			continue
+		}
+		index[id] = i
+	}
+
+	stored := make(map[uint]bool, len(items))
+	for _, item := range items {
+		stored[item.ID] = true
+		if _, ok := index[item.ID]; !ok {
+			v.add("ids", "order", fmt.Sprintf("ids is missing item %d", item.ID))
+		}
+	}
+	for i, id := range ids {
+		if !stored[id] && index[id] == i {
+			v.add(fmt.Sprintf("ids[%d]", i), "order", fmt.Sprintf("item %d is not in the portfolio", id))
+		}
+	}
+	if !v.valid() {
+		return v.errors
+	}
+
+	for i := range items {
+		items[i].Position = index[items[i].ID]
+	}
+	slices.SortFunc(items, func(a, b dbservice.PortfolioItem) int { return a.Position - b.Position })
+	return nil
+}
+
+// blankPortfolioItem reports whether item has no content
+func blankPortfolioItem(item portfolioItemRequest) bool {
+	return strings.TrimSpace(item.Title) == "" && strings.TrimSpace(item.Link) == "" &&
+		strings.TrimSpace(item.Description) == ""
+}
+
+// applyTo copies the fields present in req onto item
+func (req portfolioItemPatch) applyTo(item *dbservice.PortfolioItem) {
+	if req.Title != nil {
+		item.Title = *req.Title
+	}
+	if req.Link != nil {
+		item.Link = *req.Link
+	}
+	if req.Description != nil {
+		item.Description = *req.Description
+	}
+}
+
+// columns lists the columns an update with req writes
+func (req portfolioItemPatch) columns() []string {
+	columns := []string{"UpdatedAt"}
+	if req.Title != nil {
+		columns = append(columns, "Title")
+	}
+	if req.Link != nil {
+		columns = append(columns, "Link")
+	}
+	if req.Description != nil {
+		columns = append(columns, "Description")
+	}
+	return columns
+}
+
+func portfolioItemResponseFromModel(item *dbservice.PortfolioItem) portfolioItemResponse {
+	return portfolioItemResponse{
+		ID:          item.ID,
+		Title:       item.Title,
+		Link:        item.Link,
+		Description: item.Description,
+		Position:    item.Position,
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	dbservice "upwork-buddy/internal/database/service"
+)
+
+func TestMergePortfolio(t *testing.T) {
+	stored := []dbservice.PortfolioItem{
+		{ID: 1, ProfileID: 9, Title: "Tracker", Position: 0},
+		{ID: 2, ProfileID: 9, Title: "Shop", Position: 1},
+		{ID: 3, ProfileID: 9, Title: "Blog", Position: 2},
+	}
+	items, removed := mergePortfolio(9, stored, []portfolioItemRequest{
+		{ID: 2, Title: "Shop v2"},
+		{Title: " "},
+		{Title: "New"},
+		{ID: 1, Title: "Tracker"},
+		{ID: 1, Title: "Tracker copy"},
+		{ID: 99, Title: "Unknown"},
+	})
+
+	var got []string
+	for _, item := range items {
+		got = append(got, item.Title)
+		if item.Position != len(got)-1 || item.ProfileID != 9 {
+			t.Errorf("unexpected position or profile for %q: %d, %d", item.Title, item.Position, item.ProfileID)
+		}
+	}
+	if strings.Join(got, ",") != "Shop v2,New,Tracker,Tracker copy,Unknown" {
+		t.Errorf("unexpected items: %v", got)
+	}
+	// Stored items keep their ids; the others are inserted
+	if items[0].ID != 2 || items[1].ID != 0 || items[2].ID != 1 || items[3].ID != 0 || items[4].ID != 0 {
+		t.Errorf("unexpected ids: %+v", items)
+	}
+	if len(removed) != 1 || removed[0] != 3 {
+		t.Errorf("expected item 3 to be removed; got %v", removed)
+	}
+}
+
+func TestOrderPortfolio(t *testing.T) {
+	items := []dbservice.PortfolioItem{{ID: 1, Position: 0}, {ID: 2, Position: 1}, {ID: 3, Position: 5}}
+	if violations := orderPortfolio(items, []uint{3, 1, 2}); len(violations) > 0 {
+		t.Fatalf("unexpected violations: %+v", violations)
+	}
+	for i, id := range []uint{3, 1, 2} {
+		if items[i].ID != id || items[i].Position != i {
+			t.Errorf("expected item %d at position %d; got %+v", id, i, items[i])
+		}
+	}
+
+	violations := orderPortfolio(items, []uint{2, 2, 7})
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.Field+":"+v.Message)
+	}
+	want := "ids[1]:ids lists item 2 more than once|ids:ids is missing item 3|ids:ids is missing item 1|ids[2]:item 7 is not in the portfolio"
+	if strings.Join(got, "|") != want {
+		t.Errorf("unexpected violations:\n got: %s\nwant: %s", strings.Join(got, "|"), want)
+	}
+	if items[0].ID != 3 || items[0].Position != 0 {
+		t.Errorf("expected a rejected order to leave the items alone; got %+v", items)
+	}
+}
+
+func TestValidatePortfolioItem(t *testing.T) {
+	tests := []struct {
+		name string
+		item portfolioItemRequest
+		want string
+	}{
+		{name: "title only", item: portfolioItemRequest{Title: "Tracker"}},
+		{name: "link only", item: portfolioItemRequest{Link: "https://example.com"}},
+		{name: "blank", item: portfolioItemRequest{Title: " "}, want: "title:required"},
+		{name: "bad link", item: portfolioItemRequest{Title: "Tracker", Link: "ftp://example.com"}, want: "link:url"},
+		{name: "long title", item: portfolioItemRequest{Title: strings.Repeat("x", maxPortfolioTitleLength+1)}, want: "title:max_length"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, v := range validatePortfolioItem(tt.item) {
+				got = append(got, v.Field+":"+v.Rule)
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("expected %q; got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestOwnProfileRowsSQL(t *testing.T) {
+	var item dbservice.PortfolioItem
+	stmt := ownProfileRows(dryRunDB(t), 4).First(&item, 12).Statement
+	want := `SELECT * FROM "portfolio_items" WHERE profile_id IN (SELECT "id" FROM "profiles" WHERE user_id = $1) AND "portfolio_items"."id" = $2 ORDER BY "portfolio_items"."id" LIMIT $3`
+	if got := stmt.SQL.String(); got != want {
+		t.Errorf("unexpected query:\n got: %s\nwant: %s", got, want)
+	}
+}
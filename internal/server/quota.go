@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// quotaDay returns the UTC day that t counts against and when it ends
+func quotaDay(t time.Time) (day, resetsAt time.Time) {
+	t = t.UTC()
+	day = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day, day.AddDate(0, 0, 1)
+}
+
+// reserveAnalysis counts one analysis against the caller's daily quota. It
+// writes the error response and returns false when the quota is used up or
+// cannot be checked. The returned refund gives the analysis back, for example
+// when the LLM call fails.
+func (s *Server) reserveAnalysis(w http.ResponseWriter, r *http.Request) (refund func(), ok bool) {
+	user := userFromContext(r.Context())
+	if s.dailyQuota <= 0 || user == nil || user.ID == 0 {
+		return func() {}, true
+	}
+
+	day, resetsAt := quotaDay(time.Now())
+	var used int
+	err := s.db.GetGorm().WithContext(r.Context()).Raw(`
+		INSERT INTO analysis_usages (user_id, day, count, updated_at)
+		VALUES (?, ?, 1, NOW())
+		ON CONFLICT (user_id, day) DO UPDATE
+		SET count = analysis_usages.count + 1, updated_at = NOW()
+		RETURNING count`, user.ID, day).Scan(&used).Error
+	if err != nil {
+		writeError(w, r, codeDatabaseError, "Failed to check the daily analysis quota", nil)
+		return nil, false
+	}
+
+	refund = func() {
+		// The request context may already be cancelled when refunding
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+		defer cancel()
+		s.db.GetGorm().WithContext(ctx).Exec(`
+			UPDATE analysis_usages SET count = count - 1, updated_at = NOW()
+			WHERE user_id = ? AND day = ? AND count > 0`, user.ID, day)
+	}
+
+	w.Header().Set("Quota-Limit", strconv.Itoa(s.dailyQuota))
+	w.Header().Set("Quota-Remaining", strconv.Itoa(max(s.dailyQuota-used, 0)))
+	w.Header().Set("Quota-Reset", resetsAt.Format(http.TimeFormat))
+
+	if used > s.dailyQuota {
+		refund()
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(resetsAt).Seconds())+1))
+		writeError(w, r, codeQuotaExceeded, "Daily analysis quota used up", map[string]interface{}{
+			"limit":     s.dailyQuota,
+			"resets_at": resetsAt,
+		})
+		return nil, false
+	}
+	return refund, true
+}
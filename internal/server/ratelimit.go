@@ -0,0 +1,208 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// idleBucketTTL is how long a full bucket is kept before it is dropped
+const idleBucketTTL = 10 * time.Minute
+
+// rateLimiter is a token bucket per client. Each bucket holds up to burst
+// tokens and refills at rate tokens per second.
+type rateLimiter struct {
+	name  string
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateDecision is the outcome of one rateLimiter.allow call
+type rateDecision struct {
+	allowed   bool
+	limit     int
+	remaining int
+	// reset is the time until the bucket is full again
+	reset time.Duration
+	// retryAfter is the time until the next token when the request is denied
+	retryAfter time.Duration
+}
+
+// newRateLimiter returns a limiter allowing perMinute requests per minute
+// with bursts of up to burst requests, or nil when perMinute is not positive
+func newRateLimiter(name string, perMinute, burst int) *rateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	return &rateLimiter{
+		name:    name,
+		rate:    float64(perMinute) / 60,
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: map[string]*tokenBucket{},
+	}
+}
+
+// allow takes a token from the bucket of key if one is available
+func (l *rateLimiter) allow(key string) rateDecision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	d := rateDecision{limit: int(l.burst)}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.allowed = true
+	} else {
+		d.retryAfter = l.secondsToDuration((1 - b.tokens) / l.rate)
+	}
+	d.remaining = int(b.tokens)
+	d.reset = l.secondsToDuration((l.burst - b.tokens) / l.rate)
+	return d
+}
+
+// sweep drops buckets that have been idle long enough to be full again, at
+// most once per idleBucketTTL
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleBucketTTL {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > idleBucketTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (l *rateLimiter) secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds)) * time.Second
+}
+
+// concurrencyLimiter caps the number of in-flight requests per client
+type concurrencyLimiter struct {
+	max int
+
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+// newConcurrencyLimiter returns a limiter allowing max parallel requests per
+// client, or nil when max is not positive
+func newConcurrencyLimiter(max int) *concurrencyLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &concurrencyLimiter{max: max, inFlight: map[string]int{}}
+}
+
+// acquire reserves a slot for key. The returned release must be called when
+// the request finishes.
+func (c *concurrencyLimiter) acquire(key string) (release func(), ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.inFlight[key] >= c.max {
+		return nil, false
+	}
+	c.inFlight[key]++
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.inFlight[key]--; c.inFlight[key] <= 0 {
+			delete(c.inFlight, key)
+		}
+	}, true
+}
+
+// rateLimit applies limiter and, when set, slots to the requests of each
+// client. After authentication clients are keyed by user; before it, by IP.
+func (s *Server) rateLimit(limiter *rateLimiter, slots *concurrencyLimiter, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := clientKey(r)
+
+		if limiter != nil {
+			d := limiter.allow(key)
+			setRateLimitHeaders(w, limiter, d)
+			if !d.allowed {
+				slog.WarnContext(r.Context(), "rate limit exceeded", "limit", limiter.name, "client", key)
+				w.Header().Set("Retry-After", strconv.Itoa(int(d.retryAfter.Seconds())))
+				writeError(w, r, codeRateLimited, "Too many requests, slow down",
+					map[string]interface{}{"limit": limiter.name, "retry_after_seconds": int(d.retryAfter.Seconds())})
+				return
+			}
+		}
+
+		if slots != nil {
+			release, ok := slots.acquire(key)
+			if !ok {
+				slog.WarnContext(r.Context(), "concurrency limit exceeded", "client", key, "max_concurrent", slots.max)
+				w.Header().Set("Retry-After", "1")
+				writeError(w, r, codeRateLimited,
+					fmt.Sprintf("At most %d requests may run at once, wait for one to finish", slots.max),
+					map[string]interface{}{"limit": "concurrency", "max_concurrent": slots.max})
+				return
+			}
+			defer release()
+		}
+
+		next(w, r)
+	}
+}
+
+// setRateLimitHeaders writes the RateLimit-* headers from the IETF
+// "RateLimit header fields for HTTP" draft
+func setRateLimitHeaders(w http.ResponseWriter, limiter *rateLimiter, d rateDecision) {
+	window := int(math.Ceil(limiter.burst / limiter.rate))
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", d.limit, window))
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(d.limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(d.remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(int(d.reset.Seconds())))
+}
+
+// clientKey identifies the caller for rate limiting: the authenticated user
+// when there is one, the client IP otherwise
+func clientKey(r *http.Request) string {
+	if user := userFromContext(r.Context()); user != nil && user.ID != 0 {
+		return "user:" + strconv.FormatUint(uint64(user.ID), 10)
+	}
+	return "ip:" + clientIP(r)
+}
+
+// clientIP returns the address of the peer. Forwarding headers are ignored
+// because they can be set by the client.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterRefills(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter("test", 6, 2)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if d := limiter.allow("a"); !d.allowed {
+			t.Fatalf("request %d: expected burst to be allowed", i)
+		}
+	}
+	d := limiter.allow("a")
+	if d.allowed {
+		t.Fatal("expected request beyond burst to be denied")
+	}
+	if d.retryAfter != 10*time.Second {
+		t.Errorf("expected retry after 10s; got %v", d.retryAfter)
+	}
+	if !limiter.allow("b").allowed {
+		t.Error("expected other clients to have their own bucket")
+	}
+
+	now = now.Add(10 * time.Second)
+	if d := limiter.allow("a"); !d.allowed || d.remaining != 0 {
+		t.Errorf("expected one refilled token; got %+v", d)
+	}
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	slots := newConcurrencyLimiter(1)
+
+	release, ok := slots.acquire("a")
+	if !ok {
+		t.Fatal("expected first slot")
+	}
+	if _, ok := slots.acquire("a"); ok {
+		t.Error("expected second concurrent request to be denied")
+	}
+	if _, ok := slots.acquire("b"); !ok {
+		t.Error("expected other clients to have their own slots")
+	}
+	release()
+	if _, ok := slots.acquire("a"); !ok {
+		t.Error("expected slot to be free after release")
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	s := &Server{}
+	handler := s.rateLimit(newRateLimiter("test", 60, 1), nil, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/profile", nil)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	if rec := serve(); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "1" {
+		t.Fatalf("expected first request to pass with RateLimit headers; got %d %v", rec.Code, rec.Header())
+	}
+	rec := serve()
+	assertErrorCode(t, rec, http.StatusTooManyRequests, codeRateLimited)
+	if rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected Retry-After 1; got %q", rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestIPLimitBeforeAuthentication(t *testing.T) {
+	handler := (&Server{ipLimiter: newRateLimiter("ip", 60, 2)}).RegisterRoutes()
+	serve := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer wrong-key")
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Key guesses spend the bucket of the client IP on user and admin routes
+	for _, path := range []string{"/api/v1/profile", "/api/v1/admin/users"} {
+		assertErrorCode(t, serve(path, "192.0.2.1:1234"), http.StatusUnauthorized, codeUnauthorized)
+	}
+	rec := serve("/api/v1/admin/users", "192.0.2.1:1234")
+	assertErrorCode(t, rec, http.StatusTooManyRequests, codeRateLimited)
+	if rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected Retry-After 1; got %q", rec.Header().Get("Retry-After"))
+	}
+
+	assertErrorCode(t, serve("/api/v1/profile", "198.51.100.7:1234"), http.StatusUnauthorized, codeUnauthorized)
+}
+
+func TestQuotaDay(t *testing.T) {
+	day, resetsAt := quotaDay(time.Date(2026, 10, 18, 23, 30, 0, 0, time.FixedZone("UTC-2", -2*3600)))
+	if want := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC); !day.Equal(want) {
+		t.Errorf("expected day %v; got %v", want, day)
+	}
+	if want := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC); !resetsAt.Equal(want) {
+		t.Errorf("expected reset %v; got %v", want, resetsAt)
+	}
+}
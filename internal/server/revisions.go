@@ -0,0 +1,371 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	dbservice "upwork-buddy/internal/database/service"
+
+	"gorm.io/gorm"
+)
+
+// Page sizes of GET /api/v1/profile/revisions
+const (
+	defaultRevisionPageSize = 50
+	maxRevisionPageSize     = 200
+)
+
+// profileRevisionSummary is one entry of GET /api/v1/profile/revisions
+type profileRevisionSummary struct {
+	ID      uint `json:"id"`
+	Version int  `json:"version"`
+	// PortfolioItems counts the items of the revision
+	PortfolioItems int   `json:"portfolio_items"`
+	RestoredFrom   *uint `json:"restored_from"`
+	// Analyses counts the stored analyses that used the revision
+	Analyses  int       `json:"analyses"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// profileRevisionResponse is a revision with its content. The ids of its
+// portfolio items are the ids the items had when it was taken.
+type profileRevisionResponse struct {
+	ID             uint                    `json:"id"`
+	Version        int                     `json:"version"`
+	Description    string                  `json:"description"`
+	Skills         string                  `json:"skills"`
+	PortfolioItems []portfolioItemResponse `json:"portfolio_items"`
+	RestoredFrom   *uint                   `json:"restored_from"`
+	CreatedAt      time.Time               `json:"created_at"`
+}
+
+// profileDiffResponse lists what changed from one revision to another.
+// Description and Skills are omitted when they did not change. From is 0
+// when To is the first revision.
+type profileDiffResponse struct {
+	From           uint                  `json:"from"`
+	To             uint                  `json:"to"`
+	Description    *textChange           `json:"description,omitempty"`
+	Skills         *skillsChange         `json:"skills,omitempty"`
+	PortfolioItems []portfolioItemChange `json:"portfolio_items"`
+}
+
+type textChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// skillsChange is a changed skill list with the skills it gained and lost
+type skillsChange struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+// portfolioItemChange is a portfolio item that was added, removed or
+// changed. Fields lists what changed: title, link, description or position.
+type portfolioItemChange struct {
+	ID     uint                   `json:"id"`
+	Change string                 `json:"change"`
+	Fields []string               `json:"fields,omitempty"`
+	From   *portfolioItemResponse `json:"from,omitempty"`
+	To     *portfolioItemResponse `json:"to,omitempty"`
+}
+
+// listProfileRevisions handles GET /api/v1/profile/revisions, returning the
+// revisions of the caller's profile, newest first
+func (s *Server) listProfileRevisions(w http.ResponseWriter, r *http.Request) {
+	limit, before, violations := parseRevisionQuery(r.URL.Query())
+	if len(violations) > 0 {
+		writeError(w, r, codeValidationFailed, "The query has invalid parameters", violations)
+		return
+	}
+
+	db := s.db.GetGorm().WithContext(r.Context())
+	var revisions []profileRevisionSummary
+	if err := revisionSummaries(db, userFromContext(r.Context()).ID, limit, before).Scan(&revisions).Error; err != nil {
+		writeError(w, r, codeDatabaseError, "Failed to load profile revisions", nil)
+		return
+	}
+	if revisions == nil {
+		revisions = []profileRevisionSummary{}
+	}
+	respondWithJSON(w, revisions)
+}
+
+// getProfileRevision handles GET /api/v1/profile/revisions/{id}
+func (s *Server) getProfileRevision(w http.ResponseWriter, r *http.Request) {
+	revision, ok := s.loadRevision(w, r, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	respondWithJSON(w, revisionResponseFromModel(revision))
+}
+
+// diffProfileRevisions handles GET /api/v1/profile/revisions/{id}/diff,
+// comparing a revision with the one named by the against parameter, or by
+// default with the revision before it
+func (s *Server) diffProfileRevisions(w http.ResponseWriter, r *http.Request) {
+	to, ok := s.loadRevision(w, r, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	from := &dbservice.ProfileRevision{}
+	if against := r.URL.Query().Get("against"); against != "" {
+		if from, ok = s.loadRevision(w, r, against); !ok {
+			return
+		}
+	} else {
+		err := s.db.GetGorm().WithContext(r.Context()).
+			Preload("PortfolioItems").
+			Where("profile_id = ? AND version < ?", to.ProfileID, to.Version).
+			Order("version desc").
+			Limit(1).
+			Find(from).Error
+		if err != nil {
+			writeError(w, r, codeDatabaseError, "Failed to load profile revision", nil)
+			return
+		}
+	}
+
+	respondWithJSON(w, diffRevisions(from, to))
+}
+
+// restoreProfileRevision handles POST /api/v1/profile/revisions/{id}/restore,
+// saving the content of a revision as the current profile. The restore is a
+// new revision, so it can be undone the same way. If-Match is checked when
+// it is sent.
+func (s *Server) restoreProfileRevision(w http.ResponseWriter, r *http.Request) {
+	revision, ok := s.loadRevision(w, r, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	payload := profileRequest{Description: revision.Description, Skills: revision.Skills}
+	for _, item := range revision.PortfolioItems {
+		payload.PortfolioItems = append(payload.PortfolioItems, portfolioItemRequest{
+			ID:          item.ItemID,
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+		})
+	}
+	profile, err := s.replaceProfile(r.Context(), userFromContext(r.Context()).ID, r.Header.Get("If-Match"), payload, &revision.ID)
+	if writeProfileError(w, r, profile, err) {
+		return
+	}
+
+	slog.InfoContext(r.Context(), "profile revision restored", "revision_id", revision.ID, "version", profile.Version)
+	w.Header().Set("ETag", profileETag(profile.Version))
+	respondWithJSON(w, profileResponseFromModel(profile))
+}
+
+// loadRevision fetches the revision with the given id and its items.
+// Revisions of other users are reported as not found.
+func (s *Server) loadRevision(w http.ResponseWriter, r *http.Request, rawID string) (*dbservice.ProfileRevision, bool) {
+	id, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil {
+		writeError(w, r, codeNotFound, "Profile revision not found", nil)
+		return nil, false
+	}
+
+	db := s.db.GetGorm().WithContext(r.Context())
+	var revision dbservice.ProfileRevision
+	err = ownProfileRows(db, userFromContext(r.Context()).ID).
+		Preload("PortfolioItems", func(db *gorm.DB) *gorm.DB { return db.Order("position asc, id asc") }).
+		First(&revision, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeError(w, r, codeNotFound, "Profile revision not found", nil)
+			return nil, false
+		}
+		writeError(w, r, codeDatabaseError, "Failed to load profile revision", nil)
+		return nil, false
+	}
+	return &revision, true
+}
+
+// bumpProfile advances the version of a locked profile after a change to it
+// or its items, so saves based on an older copy fail their If-Match check,
+// and records the new state as a revision. restoredFrom names the revision
+// a restore copied.
+func bumpProfile(tx *gorm.DB, profile *dbservice.Profile, restoredFrom *uint) error {
+	profile.Version++
+	if err := tx.Model(profile).Update("version", profile.Version).Error; err != nil {
+		return err
+	}
+
+	var items []dbservice.PortfolioItem
+	if err := tx.Where("profile_id = ?", profile.ID).Order("position asc, id asc").Find(&items).Error; err != nil {
+		return err
+	}
+	return tx.Create(newRevision(profile, items, restoredFrom)).Error
+}
+
+// newRevision copies a profile and its items into a revision
+func newRevision(profile *dbservice.Profile, items []dbservice.PortfolioItem, restoredFrom *uint) *dbservice.ProfileRevision {
+	revision := &dbservice.ProfileRevision{
+		ProfileID:      profile.ID,
+		Version:        profile.Version,
+		Description:    profile.Description,
+		Skills:         profile.Skills,
+		RestoredFromID: restoredFrom,
+	}
+	for _, item := range items {
+		revision.PortfolioItems = append(revision.PortfolioItems, dbservice.ProfileRevisionItem{
+			ItemID:      item.ID,
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			Position:    item.Position,
+		})
+	}
+	return revision
+}
+
+// parseRevisionQuery reads the page size and the before parameter, the id
+// of the last revision of the previous page
+func parseRevisionQuery(values url.Values) (limit int, before uint64, violations []fieldError) {
+	var v validator
+	limit = defaultRevisionPageSize
+	if raw := values.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxRevisionPageSize {
+			v.add("limit", "range", fmt.Sprintf("limit must be a number from 1 to %d", maxRevisionPageSize))
+		}
+		limit = n
+	}
+	if raw := values.Get("before"); raw != "" {
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil || n == 0 {
+			v.add("before", "range", "before must be a revision id")
+		}
+		before = n
+	}
+	return limit, before, v.errors
+}
+
+// revisionSummaries selects a page of the user's revisions with their item
+// and analysis counts, newest first
+func revisionSummaries(db *gorm.DB, userID uint, limit int, before uint64) *gorm.DB {
+	db = ownProfileRows(db.Model(&dbservice.ProfileRevision{}), userID).
+		Select(`id, version, restored_from_id AS restored_from, created_at,
+			(SELECT COUNT(*) FROM profile_revision_items WHERE revision_id = profile_revisions.id) AS portfolio_items,
+			(SELECT COUNT(*) FROM analyses WHERE profile_revision_id = profile_revisions.id) AS analyses`)
+	if before > 0 {
+		db = db.Where("id < ?", before)
+	}
+	return db.Order("id desc").Limit(limit)
+}
+
+// diffRevisions compares two revisions. Portfolio items are matched by id
+// and listed in the order of to, followed by the removed ones.
+func diffRevisions(from, to *dbservice.ProfileRevision) profileDiffResponse {
+	diff := profileDiffResponse{From: from.ID, To: to.ID, PortfolioItems: []portfolioItemChange{}}
+	if from.Description != to.Description {
+		diff.Description = &textChange{From: from.Description, To: to.Description}
+	}
+	if from.Skills != to.Skills {
+		diff.Skills = &skillsChange{
+			From:    from.Skills,
+			To:      to.Skills,
+			Added:   missingSkills(to.Skills, from.Skills),
+			Removed: missingSkills(from.Skills, to.Skills),
+		}
+	}
+
+	before := make(map[uint]*dbservice.ProfileRevisionItem, len(from.PortfolioItems))
+	for i := range from.PortfolioItems {
+		before[from.PortfolioItems[i].ItemID] = &from.PortfolioItems[i]
+	}
+	for i := range to.PortfolioItems {
+		item := &to.PortfolioItems[i]
+		old, ok := before[item.ItemID]
+		if !ok {
+			diff.PortfolioItems = append(diff.PortfolioItems, portfolioItemChange{
+				ID: item.ItemID, Change: "added", To: revisionItemResponse(item),
+			})
+			continue
+		}
+		delete(before, item.ItemID)
+
+		var fields []string
+		if old.Title != item.Title {
+			fields = append(fields, "title")
+		}
+		if old.Link != item.Link {
+			fields = append(fields, "link")
+		}
+		if old.Description != item.Description {
+			fields = append(fields, "description")
+		}
+		if old.Position != item.Position {
+			fields = append(fields, "position")
+		}
+		if len(fields) > 0 {
+			diff.PortfolioItems = append(diff.PortfolioItems, portfolioItemChange{
+				ID: item.ItemID, Change: "changed", Fields: fields,
+				From: revisionItemResponse(old), To: revisionItemResponse(item),
+			})
+		}
+	}
+	for i := range from.PortfolioItems {
+		if item := &from.PortfolioItems[i]; before[item.ItemID] != nil {
+			diff.PortfolioItems = append(diff.PortfolioItems, portfolioItemChange{
+				ID: item.ItemID, Change: "removed", From: revisionItemResponse(item),
+			})
+		}
+	}
+	return diff
+}
+
+// missingSkills returns the skills of list that other lacks, ignoring case
+func missingSkills(list, other string) []string {
+	var have []string
+	for _, skill := range strings.Split(other, ",") {
+		have = append(have, strings.ToLower(strings.TrimSpace(skill)))
+	}
+	missing := []string{}
+	for _, skill := range strings.Split(list, ",") {
+		skill = strings.TrimSpace(skill)
+		if skill != "" && !slices.Contains(have, strings.ToLower(skill)) {
+			missing = append(missing, skill)
+		}
+	}
+	return missing
+}
+
+func revisionItemResponse(item *dbservice.ProfileRevisionItem) *portfolioItemResponse {
+	return &portfolioItemResponse{
+		ID:          item.ItemID,
+		Title:       item.Title,
+		Link:        item.Link,
+		Description: item.Description,
+		Position:    item.Position,
+	}
+}
+
+func revisionResponseFromModel(revision *dbservice.ProfileRevision) profileRevisionResponse {
+	items := make([]portfolioItemResponse, 0, len(revision.PortfolioItems))
+	for i := range revision.PortfolioItems {
+		items = append(items, *revisionItemResponse(&revision.PortfolioItems[i]))
+	}
+	return profileRevisionResponse{
+		ID:             revision.ID,
+		Version:        revision.Version,
+		Description:    revision.Description,
+		Skills:         revision.Skills,
+		PortfolioItems: items,
+		RestoredFrom:   revision.RestoredFromID,
+		CreatedAt:      revision.CreatedAt,
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	dbservice "upwork-buddy/internal/database/service"
+)
+
+func TestDiffRevisions(t *testing.T) {
+	from := &dbservice.ProfileRevision{
+		ID: 1, Description: "Go developer", Skills: "Go, PostgreSQL, Docker",
+		PortfolioItems: []dbservice.ProfileRevisionItem{
+			{ItemID: 10, Title: "Tracker", Position: 0},
+			{ItemID: 11, Title: "Shop", Position: 1},
+			{ItemID: 12, Title: "Blog", Position: 2},
+		},
+	}
+	to := &dbservice.ProfileRevision{
+		ID: 2, Description: "Go developer", Skills: "go, postgresql, Kubernetes",
+		PortfolioItems: []dbservice.ProfileRevisionItem{
+			{ItemID: 11, Title: "Shop", Link: "https://shop.example.com", Position: 0},
+			{ItemID: 10, Title: "Tracker", Position: 1},
+			{ItemID: 13, Title: "CLI", Position: 2},
+		},
+	}
+
+	diff := diffRevisions(from, to)
+	if diff.From != 1 || diff.To != 2 || diff.Description != nil {
+		t.Errorf("unexpected diff header: %+v", diff)
+	}
+	if diff.Skills == nil || strings.Join(diff.Skills.Added, ",") != "Kubernetes" || strings.Join(diff.Skills.Removed, ",") != "Docker" {
+		t.Errorf("expected Kubernetes added and Docker removed, ignoring case; got %+v", diff.Skills)
+	}
+
+	var got []string
+	for _, c := range diff.PortfolioItems {
+		got = append(got, c.Change+":"+strings.Join(c.Fields, "+"))
+	}
+	if strings.Join(got, " ") != "changed:link+position changed:position added: removed:" {
+		t.Errorf("unexpected item changes: %v", got)
+	}
+	if added := diff.PortfolioItems[2]; added.ID != 13 || added.From != nil || added.To.Title != "CLI" {
+		t.Errorf("unexpected added item: %+v", added)
+	}
+	if removed := diff.PortfolioItems[3]; removed.ID != 12 || removed.To != nil || removed.From.Title != "Blog" {
+		t.Errorf("unexpected removed item: %+v", removed)
+	}
+
+	// The first revision is compared with an empty profile
+	diff = diffRevisions(&dbservice.ProfileRevision{}, from)
+	if diff.From != 0 || diff.Description == nil || len(diff.PortfolioItems) != 3 {
+		t.Errorf("expected everything to be added; got %+v", diff)
+	}
+}
+
+func TestNewRevision(t *testing.T) {
+	restored := uint(3)
+	profile := &dbservice.Profile{ID: 5, Description: "Go developer", Skills: "Go", Version: 7}
+	revision := newRevision(profile, []dbservice.PortfolioItem{{ID: 21, Title: "Tracker", Position: 4}}, &restored)
+
+	if revision.ProfileID != 5 || revision.Version != 7 || revision.Description != "Go developer" || *revision.RestoredFromID != 3 {
+		t.Errorf("unexpected revision: %+v", revision)
+	}
+	if len(revision.PortfolioItems) != 1 || revision.PortfolioItems[0].ItemID != 21 || revision.PortfolioItems[0].Position != 4 {
+		t.Errorf("unexpected revision items: %+v", revision.PortfolioItems)
+	}
+}
+
+func TestParseRevisionQuery(t *testing.T) {
+	limit, before, violations := parseRevisionQuery(url.Values{})
+	if limit != defaultRevisionPageSize || before != 0 || len(violations) > 0 {
+		t.Errorf("expected the defaults; got %d, %d, %+v", limit, before, violations)
+	}
+
+	_, _, violations = parseRevisionQuery(url.Values{"limit": {"0"}, "before": {"latest"}})
+	var fields []string
+	for _, v := range violations {
+		fields = append(fields, v.Field+":"+v.Rule)
+	}
+	if got := strings.Join(fields, " "); got != "limit:range before:range" {
+		t.Errorf("unexpected violations: %s", got)
+	}
+}
+
+func TestRevisionSummariesSQL(t *testing.T) {
+	var revisions []profileRevisionSummary
+	stmt := revisionSummaries(dryRunDB(t), 4, 20, 9).Scan(&revisions).Statement
+	got := strings.Join(strings.Fields(stmt.SQL.String()), " ")
+	want := `SELECT id, version, restored_from_id AS restored_from, created_at, ` +
+		`(SELECT COUNT(*) FROM profile_revision_items WHERE revision_id = profile_revisions.id) AS portfolio_items, ` +
+		`(SELECT COUNT(*) FROM analyses WHERE profile_revision_id = profile_revisions.id) AS analyses ` +
+		`FROM "profile_revisions" WHERE profile_id IN (SELECT "id" FROM "profiles" WHERE user_id = $1) AND id < $2 ` +
+		`ORDER BY id desc LIMIT $3`
+	if got != want {
+		t.Errorf("unexpected query:\n got: %s\nwant: %s", got, want)
+	}
+}
@@ -2,68 +2,180 @@ package server
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"upwork-buddy/internal/metrics"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
+// supportedAPIVersions lists the API versions this server can answer, newest last
+var supportedAPIVersions = []string{"1"}
+
 func (s *Server) RegisterRoutes() http.Handler {
+	mux := s.routes()
+
+	// Wrap the mux with tracing, request ID, access log, metrics, CORS and API
+	// version middleware
+	handler := s.requestIDMiddleware(s.accessLogMiddleware(s.metricsMiddleware(mux,
+		s.corsMiddleware(s.apiVersionMiddleware(jsonMuxErrors(mux))))))
+	return otelhttp.NewHandler(handler, "http.server",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			if _, pattern := mux.Handler(r); pattern != "" {
+				return pattern
+			}
+			return r.Method + " unmatched"
+		}),
+		// Scrapes and probes would drown out the traces of real requests
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			switch r.URL.Path {
+			case "/metrics", "/livez", "/readyz", "/health":
+				return false
+			}
+			return true
+		}),
+	)
+}
+
+// routes registers every route on a new mux. Routes of the public API are
+// documented in apiOperations.
+func (s *Server) routes() *http.ServeMux {
 	mux := http.NewServeMux()
 
+	quick := routeLimit{timeout: s.requestTimeout, maxBody: defaultMaxBody}
+	probe := routeLimit{timeout: probeTimeout, maxBody: defaultMaxBody}
+
 	// Register routes
-	mux.HandleFunc("/", s.HelloWorldHandler)
+	mux.HandleFunc("GET /{$}", bounded(quick, s.HelloWorldHandler))
+
+	// Probes: /livez for liveness, /readyz for readiness. /health is the
+	// readiness check under its old name.
+	mux.HandleFunc("GET /livez", bounded(probe, s.livezHandler))
+	mux.HandleFunc("GET /readyz", bounded(probe, s.readyzHandler))
+	mux.HandleFunc("GET /health", bounded(probe, s.readyzHandler))
+
+	mux.HandleFunc("GET /metrics", bounded(quick, metrics.Handler().ServeHTTP))
 
-	mux.HandleFunc("/health", s.healthHandler)
+	mux.HandleFunc("GET /api/openapi.json", bounded(quick, s.openAPIHandler))
 
-	// AI Analysis endpoint
-	mux.HandleFunc("/api/analyze-job", s.analyzeJobHandler)
+	// Version 1 API. Analysis waits for the LLM and gets the longer deadline.
+	analyze := routeLimit{timeout: s.analyzeTimeout, maxBody: analyzeMaxBody}
+	profile := routeLimit{timeout: s.requestTimeout, maxBody: profileMaxBody}
+	jobs := routeLimit{timeout: s.requestTimeout, maxBody: jobMaxBody}
+	s.registerAPI(mux, "v1", "POST", "/analyze-job", analyze, s.rateLimit(s.analyzeLimiter, s.analyzeSlots, s.analyzeJobHandler))
+	s.registerAPI(mux, "v1", "GET", "/profile", quick, s.rateLimit(s.profileLimiter, nil, s.getProfile))
+	s.registerAPI(mux, "v1", "POST", "/profile", profile, s.rateLimit(s.profileLimiter, nil, s.saveProfile))
+	s.registerAPI(mux, "v1", "PUT", "/profile", profile, s.rateLimit(s.profileLimiter, nil, s.saveProfile))
+	s.registerAPI(mux, "v1", "POST", "/profile/portfolio-items", profile, s.rateLimit(s.profileLimiter, nil, s.createPortfolioItem))
+	s.registerAPI(mux, "v1", "PUT", "/profile/portfolio-items/order", quick, s.rateLimit(s.profileLimiter, nil, s.reorderPortfolioItems))
+	s.registerAPI(mux, "v1", "PATCH", "/profile/portfolio-items/{id}", profile, s.rateLimit(s.profileLimiter, nil, s.updatePortfolioItem))
+	s.registerAPI(mux, "v1", "DELETE", "/profile/portfolio-items/{id}", quick, s.rateLimit(s.profileLimiter, nil, s.deletePortfolioItem))
+	s.registerAPI(mux, "v1", "GET", "/profile/revisions", quick, s.rateLimit(s.profileLimiter, nil, s.listProfileRevisions))
+	s.registerAPI(mux, "v1", "GET", "/profile/revisions/{id}", quick, s.rateLimit(s.profileLimiter, nil, s.getProfileRevision))
+	s.registerAPI(mux, "v1", "GET", "/profile/revisions/{id}/diff", quick, s.rateLimit(s.profileLimiter, nil, s.diffProfileRevisions))
+	s.registerAPI(mux, "v1", "POST", "/profile/revisions/{id}/restore", quick, s.rateLimit(s.profileLimiter, nil, s.restoreProfileRevision))
 
-	// Profile configuration endpoint for the bookmarklet UI
-	mux.HandleFunc("/api/profile", s.profileHandler)
+	// Job tracking shares the profile limit
+	s.registerAPI(mux, "v1", "GET", "/jobs", quick, s.rateLimit(s.profileLimiter, nil, s.listJobs))
+	s.registerAPI(mux, "v1", "POST", "/jobs", jobs, s.rateLimit(s.profileLimiter, nil, s.createJob))
+	s.registerAPI(mux, "v1", "GET", "/jobs/{id}", quick, s.rateLimit(s.profileLimiter, nil, s.getJob))
+	s.registerAPI(mux, "v1", "PATCH", "/jobs/{id}", jobs, s.rateLimit(s.profileLimiter, nil, s.updateJob))
+	s.registerAPI(mux, "v1", "DELETE", "/jobs/{id}", quick, s.rateLimit(s.profileLimiter, nil, s.deleteJob))
+	s.registerAPI(mux, "v1", "GET", "/jobs/{id}/transitions", quick, s.rateLimit(s.profileLimiter, nil, s.listJobTransitions))
+	s.registerAPI(mux, "v1", "POST", "/jobs/{id}/transitions", quick, s.rateLimit(s.profileLimiter, nil, s.transitionJob))
 
-	// Wrap the mux with CORS middleware
-	return s.corsMiddleware(mux)
+	// User and API key management
+	s.registerAdmin(mux, "v1", "POST", "/users", quick, s.createUser)
+	s.registerAdmin(mux, "v1", "GET", "/users", quick, s.listUsers)
+	s.registerAdmin(mux, "v1", "POST", "/users/{id}/api-key", quick, s.issueAPIKey)
+	s.registerAdmin(mux, "v1", "DELETE", "/users/{id}/api-key", quick, s.revokeAPIKey)
+	return mux
 }
 
-func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+// registerAPI registers a versioned route under /api/<version> together with
+// a deprecated unversioned alias under /api kept for older bookmarklet builds.
+// Both require a user API key and are bounded by limit. Requests are limited
+// per client IP before the key is checked, so failed logins are throttled.
+func (s *Server) registerAPI(mux *http.ServeMux, version, method, path string, limit routeLimit, handler http.HandlerFunc) {
+	versioned := "/api/" + version + path
+	handler = bounded(limit, s.rateLimit(s.ipLimiter, nil, s.requireUser(handler)))
+	mux.HandleFunc(method+" "+versioned, handler)
+	mux.HandleFunc(method+" /api"+path, deprecatedAlias(versioned, handler))
+}
+
+// registerAdmin registers an admin-only route under /api/<version>/admin.
+// Admin routes have no unversioned alias and share the per-IP limit of the
+// API routes.
+func (s *Server) registerAdmin(mux *http.ServeMux, version, method, path string, limit routeLimit, handler http.HandlerFunc) {
+	mux.HandleFunc(method+" /api/"+version+"/admin"+path, bounded(limit, s.rateLimit(s.ipLimiter, nil, s.requireAdmin(handler))))
+}
+
+// deprecatedAlias marks responses from a legacy path as deprecated and points
+// clients at the versioned successor
+func deprecatedAlias(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		next(w, r)
+	}
+}
+
+var (
+	versionPathRegex   = regexp.MustCompile(`^/api/v(\d+)(/|$)`)
+	versionAcceptRegex = regexp.MustCompile(`application/vnd\.upwork-buddy\.v(\d+)\+json`)
+)
+
+// apiVersionMiddleware negotiates the API version for /api requests. The
+// version comes from the path, then the API-Version header, then an
+// "application/vnd.upwork-buddy.v<N>+json" Accept type, defaulting to the
+// oldest version. Unsupported versions are rejected with 406.
+func (s *Server) apiVersionMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*") // Replace "*" with specific origins if needed
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, X-CSRF-Token")
-		w.Header().Set("Access-Control-Allow-Credentials", "false") // Set to "true" if credentials are required
-
-		// Handle preflight OPTIONS requests
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusNoContent)
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		version := negotiateAPIVersion(r)
+		if !slices.Contains(supportedAPIVersions, version) {
+			w.Header().Set("API-Supported-Versions", strings.Join(supportedAPIVersions, ", "))
+			writeError(w, r, codeUnsupportedVersion, "API version "+version+" is not supported",
+				map[string][]string{"supported_versions": supportedAPIVersions})
 			return
 		}
 
-		// Proceed with the next handler
+		w.Header().Set("API-Version", version)
+		w.Header().Add("Vary", "API-Version, Accept")
 		next.ServeHTTP(w, r)
 	})
 }
 
-func (s *Server) HelloWorldHandler(w http.ResponseWriter, r *http.Request) {
-	resp := map[string]string{"message": "Hello World"}
-	jsonResp, err := json.Marshal(resp)
-	if err != nil {
-		http.Error(w, "Failed to marshal response", http.StatusInternalServerError)
-		return
+func negotiateAPIVersion(r *http.Request) string {
+	if m := versionPathRegex.FindStringSubmatch(r.URL.Path); m != nil {
+		return m[1]
 	}
-	w.Header().Set("Content-Type", "application/json")
-	if _, err := w.Write(jsonResp); err != nil {
-		log.Printf("Failed to write response: %v", err)
+	if v := strings.TrimPrefix(strings.TrimSpace(r.Header.Get("API-Version")), "v"); v != "" {
+		return v
+	}
+	if m := versionAcceptRegex.FindStringSubmatch(r.Header.Get("Accept")); m != nil {
+		return m[1]
 	}
+	return supportedAPIVersions[0]
 }
 
-func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
-	resp, err := json.Marshal(s.db.Health())
+func (s *Server) HelloWorldHandler(w http.ResponseWriter, r *http.Request) {
+	resp := map[string]string{"message": "Hello World"}
+	jsonResp, err := json.Marshal(resp)
 	if err != nil {
-		http.Error(w, "Failed to marshal health check response", http.StatusInternalServerError)
+		writeError(w, r, codeInternal, "Failed to marshal response", nil)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	if _, err := w.Write(resp); err != nil {
-		log.Printf("Failed to write response: %v", err)
+	if _, err := w.Write(jsonResp); err != nil {
+		slog.ErrorContext(r.Context(), "failed to write response", "error", err)
 	}
 }
@@ -1,12 +1,76 @@
 package server
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
+func TestMetricsRouteLabels(t *testing.T) {
+	handler := (&Server{}).RegisterRoutes()
+	for _, path := range []string{"/api/v1/admin/users", "/no/such/path"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`upwork_buddy_http_requests_total{method="GET",route="/api/v1/admin/users",status="401"}`,
+		`upwork_buddy_http_requests_total{method="GET",route="unmatched",status="404"}`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics to contain %s", want)
+		}
+	}
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	handler := (&Server{}).RegisterRoutes()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/users", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var body errorBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("expected error envelope; got %q", rec.Body.String())
+	}
+	if body.Error.TraceID != traceID {
+		t.Errorf("expected trace_id %s from traceparent; got %q", traceID, body.Error.TraceID)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected one server span; got %d", len(spans))
+	}
+	if name := spans[0].Name(); name != "GET /api/v1/admin/users" {
+		t.Errorf("expected span named after the route pattern; got %q", name)
+	}
+	if got := spans[0].SpanContext().TraceID().String(); got != traceID {
+		t.Errorf("expected span to continue trace %s; got %s", traceID, got)
+	}
+}
+
 func TestHandler(t *testing.T) {
 	s := &Server{}
 	server := httptest.NewServer(http.HandlerFunc(s.HelloWorldHandler))
@@ -29,3 +93,55 @@ func TestHandler(t *testing.T) {
 		t.Errorf("expected response body to be %v; got %v", expected, string(body))
 	}
 }
+
+func TestRouting(t *testing.T) {
+	handler := (&Server{}).RegisterRoutes()
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		header         map[string]string
+		wantStatus     int
+		wantAllow      string
+		wantDeprecated bool
+	}{
+		{name: "root", method: http.MethodGet, path: "/", wantStatus: http.StatusOK},
+		{name: "metrics", method: http.MethodGet, path: "/metrics", wantStatus: http.StatusOK},
+		{name: "unknown path", method: http.MethodGet, path: "/nope", wantStatus: http.StatusNotFound},
+		{name: "wrong method", method: http.MethodGet, path: "/api/v1/analyze-job", wantStatus: http.StatusMethodNotAllowed, wantAllow: "POST"},
+		{name: "versioned route without key", method: http.MethodPost, path: "/api/v1/analyze-job", wantStatus: http.StatusUnauthorized},
+		{name: "legacy alias without key", method: http.MethodPost, path: "/api/analyze-job", wantStatus: http.StatusUnauthorized, wantDeprecated: true},
+		{name: "admin route without key", method: http.MethodGet, path: "/api/v1/admin/users", wantStatus: http.StatusUnauthorized},
+		{name: "admin route has no alias", method: http.MethodGet, path: "/api/admin/users", wantStatus: http.StatusNotFound},
+		{name: "unsupported version header", method: http.MethodPost, path: "/api/analyze-job", header: map[string]string{"API-Version": "9"}, wantStatus: http.StatusNotAcceptable},
+		{name: "unsupported version path", method: http.MethodPost, path: "/api/v9/analyze-job", wantStatus: http.StatusNotAcceptable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("{"))
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d; got %d", tt.wantStatus, rec.Code)
+			}
+			if allow := rec.Header().Get("Allow"); !strings.Contains(allow, tt.wantAllow) {
+				t.Errorf("expected Allow to contain %q; got %q", tt.wantAllow, allow)
+			}
+			if deprecated := rec.Header().Get("Deprecation") != ""; deprecated != tt.wantDeprecated {
+				t.Errorf("expected deprecated=%v; got %v", tt.wantDeprecated, deprecated)
+			}
+			if rec.Header().Get("X-Request-ID") == "" {
+				t.Error("expected X-Request-ID header")
+			}
+			if rec.Code >= 400 && !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+				t.Errorf("expected JSON error response; got %q", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -1,39 +1,164 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"log/slog"
+	"net"
 	"net/http"
-	"os"
-	"strconv"
 	"time"
 
-	_ "github.com/joho/godotenv/autoload"
-
+	"upwork-buddy/internal/config"
 	"upwork-buddy/internal/database/service"
+	"upwork-buddy/internal/gemini"
+	"upwork-buddy/internal/lifecycle"
+	"upwork-buddy/internal/metrics"
 )
 
 type Server struct {
 	port int
 
 	db service.DatabaseService
+
+	// llm is shared by every request; it is nil when the provider could not
+	// be configured at boot, in which case llmErr explains why
+	llm    *gemini.Service
+	llmErr error
+	// llmRequired makes an unavailable provider fail the readiness check
+	llmRequired bool
+	llmProbe    probeCache
+
+	// adminKey is the bootstrap ADMIN_API_KEY, empty when unset
+	adminKey string
+
+	// cors lists the browser origins allowed to call the API
+	cors corsPolicy
+
+	// Per-client limits; nil limiters and a zero quota disable the check
+	analyzeLimiter *rateLimiter
+	analyzeSlots   *concurrencyLimiter
+	profileLimiter *rateLimiter
+	// ipLimiter runs before authentication, keyed by client IP
+	ipLimiter  *rateLimiter
+	dailyQuota int
+
+	// Route deadlines; zero leaves requests without one
+	requestTimeout time.Duration
+	analyzeTimeout time.Duration
+
+	// lifecycle tracks in-flight analyses and refuses new ones once
+	// shutdown has begun; nil in tests
+	lifecycle *lifecycle.Manager
+
+	httpServer *http.Server
 }
 
-func NewServer() *http.Server {
-	port, _ := strconv.Atoi(os.Getenv("PORT"))
+// NewServer connects to the database and the LLM provider, builds the
+// HTTP server described by cfg and registers its shutdown with lc
+func NewServer(cfg *config.Config, lc *lifecycle.Manager) *Server {
 	NewServer := &Server{
-		port: port,
+		port: cfg.Server.Port,
+
+		db:        service.New(cfg.Database),
+		lifecycle: lc,
+	}
+	NewServer.llm, NewServer.llmErr = newLLM(cfg.Gemini)
+	NewServer.llmRequired = cfg.Gemini.Required
+	NewServer.adminKey = cfg.Auth.AdminAPIKey
+	NewServer.cors = newCORSPolicy(cfg.CORS.AllowedOrigins, cfg.CORS.AllowCredentials)
+	slog.Info("cors configured", "allowed_origins", NewServer.cors.list(), "credentials", cfg.CORS.AllowCredentials)
+	limits := cfg.RateLimit
+	NewServer.analyzeLimiter = newRateLimiter("analyze", limits.AnalyzePerMinute, limits.AnalyzeBurst)
+	NewServer.analyzeSlots = newConcurrencyLimiter(limits.AnalyzeConcurrent)
+	NewServer.profileLimiter = newRateLimiter("profile", limits.ProfilePerMinute, limits.ProfileBurst)
+	NewServer.ipLimiter = newRateLimiter("ip", limits.IPPerMinute, limits.IPBurst)
+	NewServer.dailyQuota = limits.AnalyzeDailyQuota
+	NewServer.requestTimeout = cfg.Server.RequestTimeout
+	NewServer.analyzeTimeout = cfg.Server.AnalyzeTimeout
 
-		db: service.New(),
+	if err := metrics.RegisterDB(NewServer.db.GetDB(), "app"); err != nil {
+		slog.Warn("failed to register database metrics", "error", err)
 	}
 
 	// Declare Server config
-	server := &http.Server{
-		Addr:         fmt.Sprintf(":%d", NewServer.port),
-		Handler:      NewServer.RegisterRoutes(),
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
+	NewServer.httpServer = &http.Server{
+		Addr:    fmt.Sprintf(":%d", NewServer.port),
+		Handler: NewServer.RegisterRoutes(),
+		// Routes set their own read and write deadlines; these only
+		// bound requests that match no route
+		IdleTimeout:       time.Minute,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		// Requests are cancelled when draining passes its deadline, so
+		// running analyses answer shutting_down instead of being cut off
+		BaseContext: func(net.Listener) context.Context { return lc.Context() },
 	}
+	NewServer.registerShutdown(lc)
+
+	return NewServer
+}
+
+// newLLM creates the shared Gemini provider and validates the API key. When
+// cfg.Required is set a missing or rejected key stops the process;
+// otherwise the server starts with analysis disabled.
+func newLLM(cfg config.Gemini) (*gemini.Service, error) {
+	opts := []gemini.Option{gemini.WithAPIKey(cfg.APIKey), gemini.WithAutoContinue(cfg.AutoContinue)}
+	if cfg.Model != "" {
+		opts = append(opts, gemini.WithModel(cfg.Model))
+	}
+	if cfg.PromptVersion != "" {
+		opts = append(opts, gemini.WithPromptVersion(cfg.PromptVersion))
+	}
+
+	llm, err := gemini.New(opts...)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err = llm.Validate(ctx); err != nil {
+			llm.Close()
+			llm = nil
+		}
+	}
+
+	if err != nil {
+		if cfg.Required {
+			log.Fatalf("LLM provider unavailable: %v", err)
+		}
+		slog.Warn("llm provider unavailable, job analysis is disabled", "error", err)
+		return nil, err
+	}
+
+	slog.Info("llm provider ready", "model", llm.Model(), "prompt_version", llm.PromptVersion())
+	return llm, nil
+}
+
+// ListenAndServe starts accepting HTTP requests
+func (s *Server) ListenAndServe() error {
+	return s.httpServer.ListenAndServe()
+}
 
-	return server
+// registerShutdown stops the listener and waits for in-flight requests
+// while draining, then closes the LLM client before the database pool that
+// the last requests may still have been using
+func (s *Server) registerShutdown(lc *lifecycle.Manager) {
+	lc.OnShutdown(lifecycle.StageDrain, "http server", func(ctx context.Context) error {
+		err := s.httpServer.Shutdown(ctx)
+		if errors.Is(err, context.DeadlineExceeded) {
+			// Requests ignored the cancellation; drop their connections
+			return errors.Join(err, s.httpServer.Close())
+		}
+		return err
+	})
+	lc.OnShutdown(lifecycle.StageClose, "llm client", func(ctx context.Context) error {
+		if s.llm == nil {
+			return nil
+		}
+		return s.llm.Close()
+	})
+	lc.OnShutdown(lifecycle.StageClose, "database pool", func(ctx context.Context) error {
+		return s.db.Close()
+	})
 }
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"upwork-buddy/internal/gemini"
+)
+
+// Field limits for request payloads. They keep prompts within a sensible
+// token budget and stop oversized rows from reaching the database.
+const (
+	maxJobTitleLength       = 300
+	maxJobDescriptionLength = 20000
+	maxBudgetLength         = 200
+	maxProfileLength        = 10000
+	maxSkillsLength         = 2000
+	maxSkillsCount          = 100
+	maxPortfolioItems       = 50
+	maxPortfolioTitleLength = 200
+	maxPortfolioDescLength  = 2000
+	maxURLLength            = 2048
+	maxEmailLength          = 254
+	maxNameLength           = 200
+	maxJobSourceLength      = 50
+	maxJobNoteLength        = 2000
+)
+
+// fieldError describes one rule violated by a request field
+type fieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// validator collects every violation so clients can fix them all at once
+type validator struct {
+	errors []fieldError
+}
+
+func (v *validator) add(field, rule, message string) {
+	v.errors = append(v.errors, fieldError{Field: field, Rule: rule, Message: message})
+}
+
+// valid reports whether no rule has been violated
+func (v *validator) valid() bool {
+	return len(v.errors) == 0
+}
+
+func (v *validator) required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, "required", field+" is required")
+	}
+}
+
+func (v *validator) maxLength(field, value string, max int) {
+	if n := utf8.RuneCountInString(value); n > max {
+		v.add(field, "max_length", fmt.Sprintf("%s must be at most %d characters (got %d)", field, max, n))
+	}
+}
+
+// skillList checks the length of a comma separated skill list and the number
+// of entries in it
+func (v *validator) skillList(field, value string) {
+	v.maxLength(field, value, maxSkillsLength)
+	count := 0
+	for _, skill := range strings.Split(value, ",") {
+		if strings.TrimSpace(skill) != "" {
+			count++
+		}
+	}
+	if count > maxSkillsCount {
+		v.add(field, "max_items", fmt.Sprintf("%s must list at most %d skills (got %d)", field, maxSkillsCount, count))
+	}
+}
+
+// portfolioItem checks the fields of one portfolio item, naming them with
+// prefix
+func (v *validator) portfolioItem(prefix string, item portfolioItemRequest) {
+	v.maxLength(prefix+"title", item.Title, maxPortfolioTitleLength)
+	v.url(prefix+"link", item.Link)
+	v.maxLength(prefix+"description", item.Description, maxPortfolioDescLength)
+}
+
+// oneOf checks that a non-empty value is one of allowed
+func (v *validator) oneOf(field, value string, allowed []string) {
+	if value != "" && !slices.Contains(allowed, value) {
+		v.add(field, "one_of", field+" must be one of "+strings.Join(allowed, ", "))
+	}
+}
+
+// url checks that a non-empty value is an absolute http(s) URL
+func (v *validator) url(field, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	if len(value) > maxURLLength {
+		v.add(field, "max_length", fmt.Sprintf("%s must be at most %d characters", field, maxURLLength))
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.add(field, "url", field+" must be an absolute http or https URL")
+	}
+}
+
+// email checks that value looks like a single address. Deliverability is
+// not checked.
+func (v *validator) email(field, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	if len(value) > maxEmailLength {
+		v.add(field, "max_length", fmt.Sprintf("%s must be at most %d characters", field, maxEmailLength))
+		return
+	}
+	if addr, err := mail.ParseAddress(value); err != nil || addr.Address != value {
+		v.add(field, "email", field+" must be a valid email address")
+	}
+}
+
+// validateAnalysisRequest checks a job analysis payload before it is sent to
+// the LLM
+func validateAnalysisRequest(req gemini.JobAnalysisRequest) []fieldError {
+	var v validator
+	v.required("job_title", req.JobTitle)
+	v.maxLength("job_title", req.JobTitle, maxJobTitleLength)
+	v.required("job_description", req.JobDescription)
+	v.maxLength("job_description", req.JobDescription, maxJobDescriptionLength)
+	v.maxLength("budget", req.Budget, maxBudgetLength)
+	v.skillList("skills", req.Skills)
+	v.maxLength("user_profile", req.UserProfile, maxProfileLength)
+	v.skillList("user_skills", req.UserSkills)
+	return v.errors
+}
+
+// validateProfileRequest checks a profile payload before it is persisted
+func validateProfileRequest(req profileRequest) []fieldError {
+	var v validator
+	v.maxLength("description", req.Description, maxProfileLength)
+	v.skillList("skills", req.Skills)
+	if len(req.PortfolioItems) > maxPortfolioItems {
+		v.add("portfolio_items", "max_items",
+			fmt.Sprintf("portfolio_items must contain at most %d items (got %d)", maxPortfolioItems, len(req.PortfolioItems)))
+	}
+	for i, item := range req.PortfolioItems {
+		v.portfolioItem(fmt.Sprintf("portfolio_items[%d].", i), item)
+	}
+	return v.errors
+}
+
+// validatePortfolioItem checks a single portfolio item. Unlike a profile
+// save, which drops blank items, it rejects them.
+func validatePortfolioItem(item portfolioItemRequest) []fieldError {
+	var v validator
+	if blankPortfolioItem(item) {
+		v.add("title", "required", "a portfolio item needs a title, link or description")
+	}
+	v.portfolioItem("", item)
+	return v.errors
+}
+
+// validateCreateUserRequest checks an admin request to create a user
+func validateCreateUserRequest(req createUserRequest) []fieldError {
+	var v validator
+	v.required("email", req.Email)
+	v.email("email", req.Email)
+	v.required("name", req.Name)
+	v.maxLength("name", req.Name, maxNameLength)
+	return v.errors
+}
+
+// validateJobRequest checks a job payload. Creating a job requires a title;
+// an update only checks the fields it sets.
+func validateJobRequest(req jobRequest, create bool) []fieldError {
+	var v validator
+	if create || req.Title != nil {
+		v.required("title", deref(req.Title))
+	}
+	v.maxLength("title", deref(req.Title), maxJobTitleLength)
+	v.maxLength("description", deref(req.Description), maxJobDescriptionLength)
+	v.url("url", deref(req.URL))
+	v.maxLength("source", deref(req.Source), maxJobSourceLength)
+	if req.Status != nil {
+		v.required("status", *req.Status)
+		v.oneOf("status", normalizeStage(*req.Status), jobStages)
+	}
+	return v.errors
+}
+
+// validateJobTransitionRequest checks a request to move a job between stages
+func validateJobTransitionRequest(req jobTransitionRequest) []fieldError {
+	var v validator
+	v.required("status", req.Status)
+	v.oneOf("status", normalizeStage(req.Status), jobStages)
+	v.maxLength("note", req.Note, maxJobNoteLength)
+	return v.errors
+}
+
+// deref returns the string s points to, or "" for nil
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -2,16 +2,65 @@
  * API layer for communicating with Upwork Buddy backend
  */
 
-import type { ProfileState, ProfileApiResponse, JobInfo, AnalysisResponse } from './types';
+import type { ProfileState, ProfileApiResponse, JobInfo, AnalysisResponse, ApiErrorBody } from './types';
 import { CONFIG } from './types';
 
+/**
+ * Error raised for non-2xx responses, carrying the server's error code
+ */
+export class ApiError extends Error {
+  constructor(
+    message: string,
+    public readonly status: number,
+    public readonly code: string,
+    public readonly retryable: boolean,
+    public readonly requestId?: string
+  ) {
+    super(message);
+    this.name = 'ApiError';
+  }
+}
+
+/**
+ * Build an ApiError from a failed response, falling back to the status text
+ * when the body is not a JSON error envelope
+ */
+async function toApiError(response: Response, fallback: string): Promise<ApiError> {
+  try {
+    const body: ApiErrorBody = await response.json();
+    if (body.error) {
+      return new ApiError(body.error.message, response.status, body.error.code, body.error.retryable, body.error.request_id);
+    }
+  } catch {
+    // Not JSON; use the fallback below
+  }
+  return new ApiError(`${fallback}: ${response.status} ${response.statusText}`, response.status, 'unknown', response.status >= 500);
+}
+
+/**
+ * Build request headers carrying the user's API key, read from localStorage
+ */
+function authHeaders(extra: Record<string, string> = {}): Record<string, string> {
+  let apiKey: string | null = null;
+  try {
+    apiKey = localStorage.getItem(CONFIG.apiKeyStorageKey);
+  } catch {
+    // localStorage unavailable; the server will answer 401
+  }
+  if (!apiKey) {
+    console.warn(`No API key found; set localStorage.${CONFIG.apiKeyStorageKey}`);
+    return extra;
+  }
+  return { ...extra, Authorization: `Bearer ${apiKey}` };
+}
+
 export class ApiClient {
   /**
    * Load profile from API
    */
   async loadProfile(): Promise<ProfileState | null> {
     try {
-      const response = await fetch(CONFIG.profileApiEndpoint, { method: 'GET' });
+      const response = await fetch(CONFIG.profileApiEndpoint, { method: 'GET', headers: authHeaders() });
       if (response.ok) {
         const payload: ProfileApiResponse = await response.json();
         return this.mapProfileResponse(payload);
@@ -30,9 +79,9 @@ export class ApiClient {
   async saveProfile(profile: ProfileState): Promise<ProfileState> {
     const response = await fetch(CONFIG.profileApiEndpoint, {
       method: 'POST',
-      headers: {
+      headers: authHeaders({
         'Content-Type': 'application/json'
-      },
+      }),
       body: JSON.stringify({
         description: profile.description,
         skills: profile.skills,
@@ -41,7 +90,7 @@ export class ApiClient {
     });
 
     if (!response.ok) {
-      throw new Error(`Profile save failed: ${response.status} ${response.statusText}`);
+      throw await toApiError(response, 'Profile save failed');
     }
 
     const saved: ProfileApiResponse = await response.json();
@@ -49,9 +98,10 @@ export class ApiClient {
   }
 
   /**
-   * Analyze a job posting
+   * Analyze a job posting. cached tells the server whether an analysis of
+   * the job is already in the local cache, for its cache hit ratio metric.
    */
-  async analyzeJob(jobInfo: JobInfo, userProfile: string, userSkills: string): Promise<AnalysisResponse> {
+  async analyzeJob(jobInfo: JobInfo, userProfile: string, userSkills: string, cached = false): Promise<AnalysisResponse> {
     console.log('🚀 API: Sending analyze request', {
       title: jobInfo.title,
       descriptionLength: jobInfo.description.length,
@@ -61,11 +111,12 @@ export class ApiClient {
       userSkillsLength: userSkills.length
     });
 
-    const response = await fetch(`${CONFIG.apiBaseUrl}/api/analyze-job`, {
+    const response = await fetch(`${CONFIG.apiBaseUrl}/api/v1/analyze-job`, {
       method: 'POST',
-      headers: {
+      headers: authHeaders({
         'Content-Type': 'application/json',
-      },
+        'Client-Cache': cached ? 'hit' : 'miss',
+      }),
       body: JSON.stringify({
         job_title: jobInfo.title,
         job_description: jobInfo.description,
@@ -79,8 +130,9 @@ export class ApiClient {
     console.log('📡 API: Response status', response.status, response.statusText);
 
     if (!response.ok) {
-      console.error('❌ API: Request failed', response.status, response.statusText);
-      throw new Error(`API error: ${response.status} ${response.statusText}`);
+      const error = await toApiError(response, 'API error');
+      console.error('❌ API: Request failed', response.status, error.code, error.requestId);
+      throw error;
     }
 
     const data: AnalysisResponse = await response.json();
@@ -118,7 +118,8 @@ class UpworkBuddy {
       const profileDescription = profile.description?.trim() || CONFIG.defaultProfile;
       const profileSkills = profile.skills?.trim() || CONFIG.defaultSkills;
 
-      const analysis = await this.apiClient.analyzeJob(jobInfo, profileDescription, profileSkills);
+      const cached = this.stateManager.hasCachedAnalysis(jobInfo);
+      const analysis = await this.apiClient.analyzeJob(jobInfo, profileDescription, profileSkills, cached);
       console.log('✅ Got analysis response:', analysis);
 
       // Cache the result
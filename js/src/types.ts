@@ -21,24 +21,47 @@ export interface ProfileState {
   portfolioItems: PortfolioItem[];
 }
 
+/**
+ * Profile returned by /api/v1/profile (ProfileResponse in the OpenAPI document)
+ */
 export interface ProfileApiResponse {
   description?: string;
   skills?: string;
-  portfolio_items?: PortfolioItem[];
+  portfolio_items?: (PortfolioItem & { id?: number })[] | null;
   portfolioItems?: PortfolioItem[];
 }
 
+/**
+ * Analysis returned by POST /api/v1/analyze-job. Mirrors the
+ * JobAnalysisResponse schema of GET /api/openapi.json.
+ */
 export interface AnalysisResponse {
   proposal?: string;
   spec_sheet_prompt?: string;
-  time_estimate?: string;
-  workload_division?: string;
-  questions_for_client?: string;
-  tips_and_advice?: string;
+  // The model answers these either as prose or as a structured object
+  time_estimate?: string | Record<string, unknown>;
+  workload_division?: string | Record<string, unknown>;
+  questions_for_client?: string[] | null;
+  tips_and_advice?: string[] | null;
   tone_analysis?: string;
+  partial?: boolean;
   [key: string]: unknown;
 }
 
+/**
+ * Error envelope returned by every failing API call (see API_ERRORS.md)
+ */
+export interface ApiErrorBody {
+  error?: {
+    code: string;
+    message: string;
+    details?: unknown;
+    request_id?: string;
+    trace_id?: string;
+    retryable: boolean;
+  };
+}
+
 export interface AnalysisCache {
   jobInfo: JobInfo;
   analysis: AnalysisResponse;
@@ -50,14 +73,16 @@ export interface Config {
   apiBaseUrl: string;
   profileApiEndpoint: string;
   profileStorageKey: string;
+  apiKeyStorageKey: string;
   defaultProfile: string;
   defaultSkills: string;
 }
 
 export const CONFIG: Config = {
   apiBaseUrl: 'http://localhost:9090',
-  profileApiEndpoint: 'http://localhost:9090/api/profile',
+  profileApiEndpoint: 'http://localhost:9090/api/v1/profile',
   profileStorageKey: 'upworkBuddyProfileData',
+  apiKeyStorageKey: 'upworkBuddyApiKey',
   defaultProfile: 'Experienced full-stack developer with 10+ years in web development, specializing in Go, React, and PostgreSQL. Strong background in building scalable APIs and database-driven applications.',
   defaultSkills: 'Go, JavaScript, TypeScript, React, Node.js, PostgreSQL, MySQL, Docker, Git, RESTful APIs, GraphQL, AWS, CI/CD'
 };
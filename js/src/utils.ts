@@ -83,8 +83,8 @@ export function renderAnalysis(analysis: AnalysisResponse, showCachedIndicator =
   console.log('🎨 renderAnalysis: Field lengths:', {
     proposal: analysis.proposal?.length || 0,
     spec_sheet_prompt: analysis.spec_sheet_prompt?.length || 0,
-    time_estimate: analysis.time_estimate?.length || 0,
-    workload_division: analysis.workload_division?.length || 0,
+    time_estimate: JSON.stringify(analysis.time_estimate ?? '').length,
+    workload_division: JSON.stringify(analysis.workload_division ?? '').length,
     questions_for_client: analysis.questions_for_client?.length || 0,
     tips_and_advice: analysis.tips_and_advice?.length || 0,
     tone_analysis: analysis.tone_analysis?.length || 0
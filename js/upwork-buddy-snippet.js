@@ -5,20 +5,37 @@
  * to the Upwork Buddy API for AI analysis.
  * 
  * Usage:
- * 1. Save as bookmarklet or run in console
- * 2. Click on Upwork job listings page
- * 3. Expandable panels will appear on each job card
+ * 1. Store your API key: localStorage.upworkBuddyApiKey = '<key>'
+ * 2. Save as bookmarklet or run in console
+ * 3. Click on Upwork job listings page
+ * 4. Expandable panels will appear on each job card
  */
 
 (function() {
     'use strict';
     
     const API_BASE_URL = 'http://localhost:9090';
-    const PROFILE_API_ENDPOINT = `${API_BASE_URL}/api/profile`;
+    const PROFILE_API_ENDPOINT = `${API_BASE_URL}/api/v1/profile`;
     const PROFILE_STORAGE_KEY = 'upworkBuddyProfileData';
+    const API_KEY_STORAGE_KEY = 'upworkBuddyApiKey';
     const USER_PROFILE = 'Experienced full-stack developer with 10+ years in web development, specializing in Go, React, and PostgreSQL. Strong background in building scalable APIs and database-driven applications.';
     const USER_SKILLS = 'Go, JavaScript, TypeScript, React, Node.js, PostgreSQL, MySQL, Docker, Git, RESTful APIs, GraphQL, AWS, CI/CD';
     
+    // Build request headers carrying the user's API key, read from localStorage
+    function authHeaders(extra = {}) {
+        let apiKey = null;
+        try {
+            apiKey = localStorage.getItem(API_KEY_STORAGE_KEY);
+        } catch (error) {
+            // localStorage unavailable; the server will answer 401
+        }
+        if (!apiKey) {
+            console.warn(`No API key found; set localStorage.${API_KEY_STORAGE_KEY}`);
+            return extra;
+        }
+        return { ...extra, Authorization: `Bearer ${apiKey}` };
+    }
+
     let currentProfileState = {
         description: USER_PROFILE,
         skills: USER_SKILLS,
@@ -169,9 +186,9 @@
         try {
             const response = await fetch(PROFILE_API_ENDPOINT, {
                 method: 'POST',
-                headers: {
+                headers: authHeaders({
                     'Content-Type': 'application/json'
-                },
+                }),
                 body: JSON.stringify({
                     description: profilePayload.description,
                     skills: profilePayload.skills,
@@ -194,7 +211,7 @@
 
     async function loadProfileConfig() {
         try {
-            const response = await fetch(PROFILE_API_ENDPOINT, { method: 'GET' });
+            const response = await fetch(PROFILE_API_ENDPOINT, { method: 'GET', headers: authHeaders() });
             if (response.ok) {
                 const payload = await response.json();
                 currentProfileState = mapProfileResponse(payload);
@@ -836,11 +853,13 @@
             ? currentProfileState.skills
             : USER_SKILLS;
         
-        const response = await fetch(`${API_BASE_URL}/api/analyze-job`, {
+        const response = await fetch(`${API_BASE_URL}/api/v1/analyze-job`, {
             method: 'POST',
-            headers: {
+            headers: authHeaders({
                 'Content-Type': 'application/json',
-            },
+                // The server counts this for the client cache hit ratio
+                'Client-Cache': analyzedJobs.has(cacheKey) ? 'hit' : 'miss',
+            }),
             body: JSON.stringify({
                 job_title: jobInfo.title,
                 job_description: jobInfo.description,